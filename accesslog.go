@@ -0,0 +1,185 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidAccessLogFormat = errors.New("access log format must be one of: combined, json")
+	ErrInvalidRotation        = errors.New("access log rotation settings must not be negative")
+	ErrInvalidSize            = errors.New("size must be a non-negative number of bytes (e.g. 100MiB)")
+)
+
+// AccessRecord is a single request, as written to the access log.
+type AccessRecord struct {
+	Time      time.Time `json:"time"`
+	Client    string    `json:"client"`
+	User      string    `json:"user,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Protocol  string    `json:"protocol"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Duration  float64   `json:"duration_seconds"`
+}
+
+// AccessLog writes a line per request, separately from the console output.
+type AccessLog struct {
+	file         *RotatingFile
+	json         bool
+	errorChannel chan<- Error
+}
+
+func isValidAccessLogFormat(s string) bool {
+	switch s {
+	case "combined", "json":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseSize accepts a number of bytes with an optional unit, as in rates.
+func parseSize(s string) (int64, error) {
+	if strings.Contains(s, "/") {
+		return 0, ErrInvalidSize
+	}
+
+	size, err := parseRate(s)
+	if err != nil {
+		return 0, ErrInvalidSize
+	}
+
+	return int64(size), nil
+}
+
+func isValidSize(s string) bool {
+	_, err := parseSize(s)
+
+	return err == nil
+}
+
+func openAccessLog(errorChannel chan<- Error) (*AccessLog, error) {
+	maxSize, err := parseSize(AccessLogMaxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := openRotatingFile(AccessLogFile)
+	if err != nil {
+		return nil, err
+	}
+
+	file.MaxSize = maxSize
+	file.MaxAge = AccessLogMaxAge
+	file.Compress = AccessLogCompress
+	file.Keep = AccessLogKeep
+
+	return &AccessLog{
+		file:         file,
+		json:         AccessLogFormat == "json",
+		errorChannel: errorChannel,
+	}, nil
+}
+
+// quote escapes a value for a quoted field of the combined format, using
+// "-" for empty values as Apache does.
+func quote(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func (a *AccessLog) write(record *AccessRecord) {
+	if a == nil {
+		return
+	}
+
+	var line []byte
+
+	if a.json {
+		data, err := json.Marshal(record)
+		if err != nil {
+			a.errorChannel <- Error{Message: err}
+
+			return
+		}
+
+		line = append(data, '\n')
+	} else {
+		user := record.User
+		if user == "" {
+			user = "-"
+		}
+
+		// Combined format, followed by the duration in microseconds as
+		// with Apache's %D
+		line = fmt.Appendf(nil, "%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\" %d\n",
+			record.Client,
+			user,
+			record.Time.Format("02/Jan/2006:15:04:05 -0700"),
+			record.Method,
+			quote(record.Path),
+			record.Protocol,
+			record.Status,
+			strconv.FormatInt(record.Bytes, 10),
+			quote(record.Referer),
+			quote(record.UserAgent),
+			int64(record.Duration*1e6))
+	}
+
+	_, err := a.file.Write(line)
+	if err != nil {
+		a.errorChannel <- Error{Message: fmt.Errorf("access log: %w", err)}
+	}
+}
+
+// Reopen reopens the access log after it has been moved by logrotate.
+func (a *AccessLog) Reopen() error {
+	if a == nil {
+		return nil
+	}
+
+	return a.file.Reopen()
+}
+
+func (a *AccessLog) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	return a.file.Close()
+}
+
+// accessRecord builds the access log entry for a finished request.
+func accessRecord(r *http.Request, status int, bytes int64, started time.Time) *AccessRecord {
+	user, _, _ := r.BasicAuth()
+
+	return &AccessRecord{
+		Time:      started,
+		Client:    loggedIP(clientIP(r)),
+		User:      user,
+		Method:    r.Method,
+		Path:      r.URL.RequestURI(),
+		Protocol:  r.Proto,
+		Status:    status,
+		Bytes:     bytes,
+		Referer:   r.Referer(),
+		UserAgent: r.UserAgent(),
+		Duration:  time.Since(started).Seconds(),
+	}
+}
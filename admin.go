@@ -0,0 +1,554 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// Largest request body accepted as a blob, since it is held in memory
+	maxBlobSize = 256 << 20
+)
+
+var (
+	ErrNoAdminToken = errors.New("--admin-token is required unless the admin API listens on a Unix socket")
+	ErrUnauthorized = errors.New("missing or invalid admin token")
+)
+
+// FileStatus describes a single file of a share in admin API responses.
+type FileStatus struct {
+	Name   string   `json:"name"`
+	Path   string   `json:"path"`
+	Size   int64    `json:"size"`
+	SHA256 string   `json:"sha256,omitempty"`
+	URLs   []string `json:"urls"`
+}
+
+// ShareStatus describes a share in admin API responses.
+type ShareStatus struct {
+	Slug      string       `json:"slug"`
+	Status    string       `json:"status"`
+	Count     int          `json:"count"`
+	Served    int          `json:"served"`
+	Remaining *int         `json:"remaining,omitempty"`
+	Expire    time.Time    `json:"expire,omitzero"`
+	NotBefore time.Time    `json:"not_before,omitzero"`
+	Files     []FileStatus `json:"files"`
+}
+
+// ShareUpdate is the body of a PATCH request. Count replaces the share's
+// download limit, AddCount raises it, and Expire replaces its expiry, with
+// an empty string removing it.
+type ShareUpdate struct {
+	Count    *int    `json:"count,omitempty"`
+	AddCount int     `json:"add_count,omitempty"`
+	Expire   *string `json:"expire,omitempty"`
+}
+
+// RateLimits is the body of requests to the rate endpoint, using the same
+// syntax as --rate and --rate-per-client.
+type RateLimits struct {
+	Rate          *string `json:"rate,omitempty"`
+	RatePerClient *string `json:"rate_per_client,omitempty"`
+}
+
+type adminAPI struct {
+	registry *Registry
+	limits   *Limits
+	bases    []string
+}
+
+func shareStatus(s *Share, bases []string, now time.Time) ShareStatus {
+	count, served := s.counts()
+
+	s.mu.Lock()
+	expire, notBefore := s.Expire, s.NotBefore
+	s.mu.Unlock()
+
+	status := ShareStatus{
+		Slug:      strings.TrimPrefix(s.Slug, "/"),
+		Status:    s.status(now),
+		Count:     count,
+		Served:    served,
+		Expire:    expire,
+		NotBefore: notBefore,
+	}
+
+	files := s.registry.files(s)
+
+	status.Files = make([]FileStatus, 0, len(files))
+
+	if count != 0 {
+		remaining := max(count-served, 0)
+
+		status.Remaining = &remaining
+	}
+
+	for _, f := range files {
+		status.Files = append(status.Files, FileStatus{
+			Name:   strings.TrimPrefix(f.Name, "/"),
+			Path:   f.Path,
+			Size:   f.Size(),
+			SHA256: f.Checksum(),
+			URLs:   fileURLs(bases, s, f),
+		})
+	}
+
+	return status
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// authenticate requires the given bearer token, if it is not empty.
+func authenticate(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="send"`)
+
+				writeJSONError(w, http.StatusUnauthorized, ErrUnauthorized)
+
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *adminAPI) share(w http.ResponseWriter, p httprouter.Params) *Share {
+	slug := "/" + p.ByName("slug")
+
+	share := a.registry.lookupShare(slug)
+	if share == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrNoShare, slug))
+	}
+
+	return share
+}
+
+func (a *adminAPI) listShares(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	now := time.Now()
+
+	shares := a.registry.list()
+
+	statuses := make([]ShareStatus, 0, len(shares))
+
+	for _, s := range shares {
+		statuses = append(statuses, shareStatus(s, a.bases, now))
+	}
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (a *adminAPI) getShare(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	share := a.share(w, p)
+	if share == nil {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, shareStatus(share, a.bases, time.Now()))
+}
+
+func (a *adminAPI) created(w http.ResponseWriter, share *Share) {
+	status := shareStatus(share, a.bases, time.Now())
+
+	for _, f := range status.Files {
+		for _, url := range f.URLs {
+			logEvent(slog.LevelInfo, "share_registered", fmt.Sprintf("Admin: %s -> %s", url, f.Path),
+				"slug", share.Slug,
+				"url", url,
+				"path", f.Path,
+				"source", "admin")
+		}
+	}
+
+	a.limits.emit(shareEvent("share_created", "admin", share, a.bases))
+
+	writeJSON(w, http.StatusCreated, status)
+}
+
+// addShare publishes files already present on the server, described in the
+// same form as a manifest entry.
+func (a *adminAPI) addShare(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	config := &ShareConfig{}
+
+	decoder := json.NewDecoder(r.Body)
+
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(config)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	share, err := config.newShare(time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	err = a.registry.add(share)
+	if err != nil {
+		writeJSONError(w, http.StatusConflict, err)
+
+		return
+	}
+
+	for _, path := range config.Paths {
+		if path == "" {
+			err = ErrNoPaths
+
+			break
+		}
+
+		_, _, err = registerHandler(a.registry, path, share, a.bases)
+		if err != nil {
+			break
+		}
+	}
+
+	if err == nil && len(share.Files) == 0 {
+		err = ErrNoFile
+	}
+
+	if err != nil {
+		a.registry.remove(share)
+
+		writeJSONError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	a.created(w, share)
+}
+
+// addBlob publishes the request body as a single file, with the share's
+// settings given as query parameters.
+func (a *adminAPI) addBlob(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	query := r.URL.Query()
+
+	config := &ShareConfig{
+		Paths:     []string{""},
+		Slug:      query.Get("slug"),
+		Expire:    query.Get("expire"),
+		NotBefore: query.Get("not_before"),
+		Password:  query.Get("password"),
+		Allow:     query["allow"],
+	}
+
+	if value := query.Get("count"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, ErrInvalidCount)
+
+			return
+		}
+
+		config.Count = &count
+	}
+
+	if value := query.Get("burn"); value != "" {
+		burn, err := strconv.ParseBool(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+
+			return
+		}
+
+		config.Burn = &burn
+	}
+
+	share, err := config.newShare(time.Now())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBlobSize))
+
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &tooLarge):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, err)
+
+		return
+	case err != nil:
+		writeJSONError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	name := query.Get("name")
+	if strings.Contains(name, "/") {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("%w: %q", ErrInvalidName, name))
+
+		return
+	}
+
+	filename := fileName(share, "")
+	if name != "" && !share.Randomize {
+		filename = "/" + name
+	}
+
+	err = a.registry.add(share)
+	if err != nil {
+		writeJSONError(w, http.StatusConflict, err)
+
+		return
+	}
+
+	file := &File{
+		Name:    filename,
+		Path:    "<data from admin API>",
+		content: content,
+		inline:  true,
+	}
+
+	_, err = publishFile(a.registry, share, file, a.bases)
+	if err != nil {
+		a.registry.remove(share)
+
+		writeJSONError(w, http.StatusConflict, err)
+
+		return
+	}
+
+	a.created(w, share)
+}
+
+func (a *adminAPI) updateShare(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	share := a.share(w, p)
+	if share == nil {
+		return
+	}
+
+	update := &ShareUpdate{}
+
+	decoder := json.NewDecoder(r.Body)
+
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(update)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	if update.Count != nil && *update.Count < 0 || update.AddCount < 0 {
+		writeJSONError(w, http.StatusBadRequest, ErrInvalidCount)
+
+		return
+	}
+
+	var expire *time.Time
+
+	if update.Expire != nil {
+		t, err := parseTime(*update.Expire, time.Now())
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+
+			return
+		}
+
+		expire = &t
+	}
+
+	if update.AddCount > 0 {
+		share.extend(update.AddCount)
+	}
+
+	share.update(update.Count, expire)
+
+	status := shareStatus(share, a.bases, time.Now())
+
+	logEvent(slog.LevelInfo, "share_updated", fmt.Sprintf("Admin: Updated %s (%s)", share.Slug, status.Status),
+		"slug", share.Slug,
+		"status", status.Status,
+		"count", status.Count,
+		"expire", status.Expire)
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (a *adminAPI) revokeShare(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	share, err := a.registry.revoke("/" + p.ByName("slug"))
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+
+		return
+	}
+
+	logEvent(slog.LevelInfo, "share_revoked", fmt.Sprintf("Admin: Revoked %s", share.Slug),
+		"slug", share.Slug)
+
+	a.limits.emit(&Event{Type: "share_revoked", Source: "admin", Slug: share.Slug})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *adminAPI) rates() RateLimits {
+	rate, ratePerClient := a.limits.throttle.Rates()
+
+	total, perClient := formatRate(rate), formatRate(ratePerClient)
+
+	return RateLimits{Rate: &total, RatePerClient: &perClient}
+}
+
+func (a *adminAPI) getRate(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	writeJSON(w, http.StatusOK, a.rates())
+}
+
+func (a *adminAPI) setRate(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+	limits := &RateLimits{}
+
+	decoder := json.NewDecoder(r.Body)
+
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(limits)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	rate, ratePerClient := a.limits.throttle.Rates()
+
+	if limits.Rate != nil {
+		rate, err = parseRate(*limits.Rate)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+
+			return
+		}
+	}
+
+	if limits.RatePerClient != nil {
+		ratePerClient, err = parseRate(*limits.RatePerClient)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err)
+
+			return
+		}
+	}
+
+	a.limits.throttle.Set(rate, ratePerClient)
+
+	logRates(rate, ratePerClient, "Admin: ")
+
+	writeJSON(w, http.StatusOK, a.rates())
+}
+
+// adminServer serves the admin API on a single listener.
+type adminServer struct {
+	*http.Server
+
+	name     string
+	listener *Listener
+}
+
+func (a *adminServer) serve() error {
+	if a.listener.TLS {
+		return a.ServeTLS(a.listener, TLSCert, TLSKey)
+	}
+
+	return a.Serve(a.listener)
+}
+
+func newAdminServer(name string, listener *Listener, token string, api *adminAPI) *adminServer {
+	mux := httprouter.New()
+
+	mux.GET("/shares", api.listShares)
+	mux.POST("/shares", api.addShare)
+	mux.GET("/shares/:slug", api.getShare)
+	mux.PATCH("/shares/:slug", api.updateShare)
+	mux.DELETE("/shares/:slug", api.revokeShare)
+	mux.POST("/blobs", api.addBlob)
+	mux.GET("/rate", api.getRate)
+	mux.PUT("/rate", api.setRate)
+	mux.Handler("GET", "/metrics", metricsHandler(api.registry, api.limits))
+
+	return &adminServer{
+		Server: &http.Server{
+			Handler:           authenticate(mux, token),
+			IdleTimeout:       KeepAliveTimeout,
+			ReadHeaderTimeout: ReadHeaderTimeout,
+		},
+		name:     name,
+		listener: listener,
+	}
+}
+
+// openAdminServers opens the admin API given by --admin, and the control
+// socket when running as a daemon. The control socket is only accessible to
+// the user running send, so it does not require the admin token.
+func openAdminServers(registry *Registry, limits *Limits, bases []string) ([]*adminServer, error) {
+	api := &adminAPI{
+		registry: registry,
+		limits:   limits,
+		bases:    bases,
+	}
+
+	var servers []*adminServer
+
+	if Admin != "" {
+		systemd, err := systemdListeners()
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := openListener(Admin, systemd)
+		if err != nil {
+			return nil, err
+		}
+
+		servers = append(servers, newAdminServer("Admin API", l, AdminToken, api))
+	}
+
+	if Daemon {
+		l, err := listenControl(socketPath())
+		if err != nil {
+			for _, s := range servers {
+				s.listener.Close()
+			}
+
+			return nil, err
+		}
+
+		servers = append(servers, newAdminServer("Control socket", l, "", api))
+	}
+
+	return servers, nil
+}
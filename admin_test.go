@@ -0,0 +1,146 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestAdmin(token string) (*adminAPI, http.Handler) {
+	api := &adminAPI{
+		registry: newRegistry(true),
+		limits:   newTestLimits(),
+		bases:    []string{"http://host"},
+	}
+
+	return api, newAdminServer("Admin API", nil, token, api).Handler
+}
+
+func adminRequest(t *testing.T, handler http.Handler, method, target, token string, body io.Reader) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, target, body)
+
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestAdminAuthentication(t *testing.T) {
+	_, handler := newTestAdmin("secret")
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer guess", http.StatusUnauthorized},
+		{"not bearer", "Basic secret", http.StatusUnauthorized},
+		{"valid", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/shares", nil)
+
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: rejected without a WWW-Authenticate header", tt.name)
+		}
+	}
+}
+
+func TestAdminCreateAndRevoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+
+	err := os.WriteFile(path, []byte("quarterly"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, handler := newTestAdmin("secret")
+
+	w := adminRequest(t, handler, http.MethodPost, "/shares", "secret",
+		strings.NewReader(`{"slug": "reports", "paths": ["`+path+`"], "count": 2}`))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create share: status = %d (%s), want %d", w.Code, w.Body, http.StatusCreated)
+	}
+
+	w = adminRequest(t, handler, http.MethodPost, "/blobs?slug=notes&name=notes.txt", "secret", strings.NewReader("hello"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create blob: status = %d (%s), want %d", w.Code, w.Body, http.StatusCreated)
+	}
+
+	var status ShareStatus
+
+	err = json.Unmarshal(w.Body.Bytes(), &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.Slug != "notes" || len(status.Files) != 1 || status.Files[0].Size != 5 ||
+		status.Files[0].URLs[0] != "http://host/notes/notes.txt" {
+		t.Errorf("created blob = %+v", status)
+	}
+
+	w = adminRequest(t, handler, http.MethodPost, "/blobs?slug=notes", "secret", strings.NewReader("again"))
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate slug: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	w = adminRequest(t, handler, http.MethodPost, "/blobs?name=a/b.txt", "secret", strings.NewReader("hello"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), ErrInvalidName.Error()) {
+		t.Errorf("name with a slash: status = %d (%s), want %d", w.Code, w.Body, http.StatusBadRequest)
+	}
+
+	var statuses []ShareStatus
+
+	w = adminRequest(t, handler, http.MethodGet, "/shares", "secret", nil)
+
+	err = json.Unmarshal(w.Body.Bytes(), &statuses)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("listed shares = %s, want two", w.Body)
+	}
+
+	w = adminRequest(t, handler, http.MethodDelete, "/shares/notes", "secret", nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("revoke: status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	if api.registry.lookupShare("/notes") != nil {
+		t.Error("revoked share is still registered")
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		w = adminRequest(t, handler, method, "/shares/notes", "secret", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s revoked share: status = %d, want %d", method, w.Code, http.StatusNotFound)
+		}
+	}
+}
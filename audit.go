@@ -0,0 +1,446 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAuditChain          = errors.New("audit log hash chain is broken")
+	ErrAuditSignature      = errors.New("audit log signature is invalid")
+	ErrAuditUnsigned       = errors.New("audit log is not fully signed")
+	ErrInvalidKey          = errors.New("key file does not contain an ed25519 key")
+	ErrInvalidSignInterval = errors.New("audit log signing interval must be a non-negative duration")
+	ErrNoAuditLog          = errors.New("an audit key requires --audit-log")
+)
+
+// Hash of the record preceding the first one in an audit log
+var auditGenesis = strings.Repeat("0", 2*sha256.Size)
+
+// AuditRecord is a single line of the audit log. Prev is the SHA-256 of the
+// previous line, so that edits and deletions break the chain. Signature
+// records carry an ed25519 signature over Prev, vouching for every record
+// before them.
+type AuditRecord struct {
+	Seq  uint64 `json:"seq"`
+	Prev string `json:"prev"`
+
+	*Event
+
+	Key       string `json:"key,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// AuditLog appends hash-chained records to a file, signing the chain
+// periodically and on close if it has a key.
+type AuditLog struct {
+	mu       sync.Mutex
+	f        *os.File
+	seq      uint64
+	prev     string
+	key      ed25519.PrivateKey
+	unsigned int
+	stop     chan struct{}
+
+	errorChannel chan<- Error
+}
+
+func hashLine(line []byte) string {
+	sum := sha256.Sum256(line)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// lastLine returns the final non-empty line of the file.
+func lastLine(f *os.File) ([]byte, error) {
+	_, err := f.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	var last []byte
+
+	scanner := bufio.NewScanner(f)
+
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+
+	return last, scanner.Err()
+}
+
+// openAuditLog opens the audit log for appending, continuing the chain of
+// any records already in it.
+func openAuditLog(path, keyPath string, interval time.Duration, errorChannel chan<- Error) (*AuditLog, error) {
+	a := &AuditLog{
+		prev:         auditGenesis,
+		stop:         make(chan struct{}),
+		errorChannel: errorChannel,
+	}
+
+	if keyPath != "" {
+		key, err := readPrivateKey(keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		a.key = key
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := lastLine(f)
+	if err != nil {
+		f.Close()
+
+		return nil, err
+	}
+
+	if last != nil {
+		record := &AuditRecord{}
+
+		err = json.Unmarshal(last, record)
+		if err != nil {
+			f.Close()
+
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		a.seq, a.prev = record.Seq, hashLine(last)
+	}
+
+	a.f = f
+
+	if a.key != nil && interval > 0 {
+		go a.signPeriodically(interval)
+	}
+
+	return a, nil
+}
+
+func (a *AuditLog) append(record *AuditRecord) error {
+	record.Seq = a.seq + 1
+	record.Prev = a.prev
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.f.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+
+	a.seq, a.prev = record.Seq, hashLine(line)
+
+	return nil
+}
+
+func (a *AuditLog) record(e *Event) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := a.append(&AuditRecord{Event: e})
+	if err != nil {
+		a.errorChannel <- Error{Message: fmt.Errorf("audit log: %w", err)}
+
+		return
+	}
+
+	a.unsigned++
+}
+
+// sign appends a signature over the chain so far, if anything has been
+// recorded since the last one.
+func (a *AuditLog) sign() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.key == nil || a.unsigned == 0 {
+		return nil
+	}
+
+	head, err := hex.DecodeString(a.prev)
+	if err != nil {
+		return err
+	}
+
+	record := &AuditRecord{
+		Event:     &Event{Type: "signature", Time: time.Now()},
+		Key:       base64.StdEncoding.EncodeToString(a.key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(a.key, head)),
+	}
+
+	err = a.append(record)
+	if err != nil {
+		return err
+	}
+
+	a.unsigned = 0
+
+	return nil
+}
+
+func (a *AuditLog) signPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := a.sign()
+			if err != nil {
+				a.errorChannel <- Error{Message: fmt.Errorf("audit log: %w", err)}
+			}
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// Close signs any records not yet covered by a signature.
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	close(a.stop)
+
+	err := a.sign()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return errors.Join(err, a.f.Close())
+}
+
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, path)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, path)
+	}
+
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, path)
+	}
+
+	return private, nil
+}
+
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, path)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, path)
+	}
+
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, path)
+	}
+
+	return public, nil
+}
+
+// generateAuditKey writes a new ed25519 private key to path, and its public
+// key to path with .pub appended.
+func generateAuditKey(path string) error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	err = os.WriteFile(path+".pub", publicPEM, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote private key to %s and public key to %s.pub\n", path, path)
+
+	return nil
+}
+
+// verifyAuditLog checks the hash chain and every signature in the audit log,
+// against the given public key or, if it is nil, the key embedded in each
+// signature record.
+func verifyAuditLog(path string, public ed25519.PublicKey) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	scanner.Buffer(nil, 1<<20)
+
+	prev := auditGenesis
+
+	var seq uint64
+
+	var signatures, unsigned int
+
+	var lastSigned time.Time
+
+	// Without a trusted key, every signature must at least be made with the
+	// same key, so that records cannot be appended and signed by someone else
+	var signer ed25519.PublicKey
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		record := &AuditRecord{}
+
+		err = json.Unmarshal(line, record)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", seq+1, err)
+		}
+
+		if record.Seq != seq+1 {
+			return fmt.Errorf("%w: expected record %d, found %d", ErrAuditChain, seq+1, record.Seq)
+		}
+
+		if record.Prev != prev {
+			return fmt.Errorf("%w: record %d does not follow record %d", ErrAuditChain, record.Seq, seq)
+		}
+
+		if record.Signature != "" {
+			key := public
+
+			if key == nil {
+				embedded, err := base64.StdEncoding.DecodeString(record.Key)
+				if err != nil || len(embedded) != ed25519.PublicKeySize {
+					return fmt.Errorf("%w: record %d has an invalid key", ErrAuditSignature, record.Seq)
+				}
+
+				if signer != nil && !signer.Equal(ed25519.PublicKey(embedded)) {
+					return fmt.Errorf("%w: record %d is signed with a different key than earlier records", ErrAuditSignature, record.Seq)
+				}
+
+				key, signer = embedded, embedded
+			}
+
+			signature, err := base64.StdEncoding.DecodeString(record.Signature)
+			if err != nil {
+				return fmt.Errorf("%w: record %d", ErrAuditSignature, record.Seq)
+			}
+
+			head, err := hex.DecodeString(record.Prev)
+			if err != nil || !ed25519.Verify(key, head, signature) {
+				return fmt.Errorf("%w: record %d", ErrAuditSignature, record.Seq)
+			}
+
+			signatures++
+
+			unsigned = 0
+
+			if record.Event != nil {
+				lastSigned = record.Time
+			}
+		} else {
+			unsigned++
+		}
+
+		seq, prev = record.Seq, hashLine(line)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: %d record(s), hash chain intact, %d valid signature(s)\n", path, seq, signatures)
+
+	if public == nil && signatures > 0 {
+		fmt.Println("Signatures were checked against the keys embedded in the log; use --public-key to check them against a trusted key")
+	}
+
+	switch {
+	case signatures == 0:
+		return fmt.Errorf("%w: no signatures found, so records removed from its end cannot be detected", ErrAuditUnsigned)
+	case unsigned > 0:
+		return fmt.Errorf("%w: the last %d record(s) are not covered by a signature, so the log may have been truncated after %s",
+			ErrAuditUnsigned, unsigned, lastSigned.Format(logDate))
+	}
+
+	return nil
+}
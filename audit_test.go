@@ -0,0 +1,122 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// appendAudit records events in the audit log at path, signed with the key
+// at keyPath if there is one, leaving them unsigned if sign is false.
+func appendAudit(t *testing.T, path, keyPath string, events int, sign bool) {
+	t.Helper()
+
+	a, err := openAuditLog(path, keyPath, 0, make(chan Error, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range events {
+		a.record(&Event{Type: "download_completed", Time: time.Now(), Slug: "/test"})
+	}
+
+	if !sign {
+		a.key = nil
+	}
+
+	err = a.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func auditKey(t *testing.T, dir, name string) (string, ed25519.PublicKey) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	err := generateAuditKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public, err := readPublicKey(path + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path, public
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	dir := t.TempDir()
+
+	key, public := auditKey(t, dir, "key.pem")
+	otherKey, otherPublic := auditKey(t, dir, "other.pem")
+
+	tests := []struct {
+		name   string
+		write  func(path string)
+		public ed25519.PublicKey
+		err    error
+	}{
+		{"signed", func(path string) {
+			appendAudit(t, path, key, 3, true)
+			appendAudit(t, path, key, 2, true)
+		}, nil, nil},
+		{"signed with trusted key", func(path string) {
+			appendAudit(t, path, key, 3, true)
+		}, public, nil},
+		{"signed with untrusted key", func(path string) {
+			appendAudit(t, path, key, 3, true)
+		}, otherPublic, ErrAuditSignature},
+		{"key changed", func(path string) {
+			appendAudit(t, path, key, 3, true)
+			appendAudit(t, path, otherKey, 2, true)
+		}, nil, ErrAuditSignature},
+		{"not signed", func(path string) {
+			appendAudit(t, path, "", 3, false)
+		}, nil, ErrAuditUnsigned},
+		{"unsigned tail", func(path string) {
+			appendAudit(t, path, key, 3, true)
+			appendAudit(t, path, key, 2, false)
+		}, nil, ErrAuditUnsigned},
+		{"edited", func(path string) {
+			appendAudit(t, path, key, 3, true)
+
+			data, _ := os.ReadFile(path)
+
+			os.WriteFile(path, bytes.Replace(data, []byte(`"/test"`), []byte(`"/edit"`), 1), 0640)
+		}, nil, ErrAuditChain},
+		{"truncated", func(path string) {
+			appendAudit(t, path, key, 3, true)
+
+			data, _ := os.ReadFile(path)
+
+			lines := bytes.SplitAfter(data, []byte("\n"))
+
+			os.WriteFile(path, bytes.Join(lines[:2], nil), 0640)
+		}, nil, ErrAuditUnsigned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+
+			tt.write(path)
+
+			err := verifyAuditLog(path, tt.public)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("verifyAuditLog() = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrBurnCancelled    = errors.New("cancelled, nothing will be deleted")
+	ErrBurnNotRegular   = errors.New("only regular files can be burned")
+	ErrBurnReplaced     = errors.New("file was replaced while being burned")
+	ErrBurnWithoutCount = errors.New("burning files requires a download count")
+)
+
+// burnFile overwrites a file with random data before removing it, so that
+// its content cannot be recovered from the blocks it occupied. Copy-on-write
+// and journaling filesystems, and SSDs, may keep older copies regardless.
+// A symlink is only removed, leaving whatever it points to alone.
+func burnFile(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return os.Remove(path)
+	case !info.Mode().IsRegular():
+		return fmt.Errorf("%w: %s", ErrBurnNotRegular, path)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	opened, err := f.Stat()
+	if err == nil && !os.SameFile(info, opened) {
+		err = ErrBurnReplaced
+	}
+	if err == nil {
+		_, err = io.CopyN(f, rand.Reader, opened.Size())
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+
+	f.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// burnPaths returns the files on disk which burning a share deletes: the
+// source of each file, or its copy in the state directory if it came from
+// stdin or the admin API. Command output and objects in S3 are left alone.
+func burnPaths(s *Share) []string {
+	var paths []string
+
+	for _, f := range s.registry.files(s) {
+		switch {
+		case f.command != "" || f.object != nil:
+		case !f.inline:
+			paths = append(paths, f.Path)
+		case f.spool != "" && s.registry.state != nil && !spoolShared(s, f.spool):
+			paths = append(paths, filepath.Join(s.registry.state.dir, spoolDir, f.spool))
+		}
+	}
+
+	return paths
+}
+
+// spoolShared reports whether a share other than s has a file in the given
+// spool. Identical content is only spooled once, so it must be left for the
+// other share, and is removed along with it.
+func spoolShared(s *Share, spool string) bool {
+	for _, other := range s.registry.list() {
+		if other == s {
+			continue
+		}
+
+		for _, f := range s.registry.files(other) {
+			if f.spool == spool {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// burn deletes the files behind a share once its last download has
+// completed.
+func burn(s *Share, limits *Limits) error {
+	paths := burnPaths(s)
+
+	var errs []error
+
+	for _, path := range paths {
+		err := burnFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("burning %s: %w", path, err))
+
+			continue
+		}
+
+		logEvent(slog.LevelInfo, "file_burned", fmt.Sprintf("Burned %s", path),
+			"slug", s.Slug,
+			"path", path)
+	}
+
+	limits.emit(&Event{Type: "share_burned", Slug: s.Slug, Paths: paths})
+
+	return errors.Join(errs...)
+}
+
+// isInteractive reports whether stdin is a terminal, rather than a pipe or
+// /dev/null as for services.
+func isInteractive() bool {
+	if isFromPipe() {
+		return false
+	}
+
+	stdin, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+
+	null, err := os.Stat(os.DevNull)
+	if err != nil {
+		return true
+	}
+
+	return !os.SameFile(stdin, null)
+}
+
+// confirmBurn lists the files which will be deleted, including any which
+// will be written to the watched directories dirs, and asks for confirmation
+// when run interactively.
+func confirmBurn(paths, dirs []string) error {
+	if len(paths) == 0 && len(dirs) == 0 {
+		return nil
+	}
+
+	for _, path := range paths {
+		logEvent(slog.LevelWarn, "burn_pending", fmt.Sprintf("Will delete %s after the final download", path),
+			"path", path)
+	}
+
+	for _, dir := range dirs {
+		logEvent(slog.LevelWarn, "burn_pending", fmt.Sprintf("Will delete each file in %s after its final download", dir),
+			"path", dir)
+	}
+
+	if BurnYes || !isInteractive() {
+		return nil
+	}
+
+	fmt.Fprint(os.Stderr, "Delete these files after their final download? [y/N] ")
+
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return ErrBurnCancelled
+	}
+}
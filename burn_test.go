@@ -0,0 +1,96 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBurnFile(t *testing.T) {
+	dir := t.TempDir()
+
+	content := bytes.Repeat([]byte("secret "), 1000)
+
+	path := filepath.Join(dir, "secret.txt")
+
+	err := os.WriteFile(path, content, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second link to the same blocks shows what they hold after burning
+	witness := filepath.Join(dir, "witness")
+
+	err = os.Link(path, witness)
+	if err != nil {
+		t.Skipf("hard links are not supported: %v", err)
+	}
+
+	err = burnFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Lstat(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("burned file still exists: %v", err)
+	}
+
+	burned, err := os.ReadFile(witness)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(burned) != len(content) || bytes.Contains(burned, []byte("secret")) {
+		t.Errorf("content was not overwritten before removal")
+	}
+
+	err = burnFile(path)
+	if err != nil {
+		t.Errorf("burning a missing file = %v, want nil", err)
+	}
+
+	err = burnFile(dir)
+	if !errors.Is(err, ErrBurnNotRegular) {
+		t.Errorf("burning a directory = %v, want %v", err, ErrBurnNotRegular)
+	}
+}
+
+func TestBurnFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+
+	target := filepath.Join(dir, "target.txt")
+
+	err := os.WriteFile(target, []byte("keep me"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(dir, "link.txt")
+
+	err = os.Symlink(target, link)
+	if err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+
+	err = burnFile(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Lstat(link)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("symlink still exists: %v", err)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "keep me" {
+		t.Errorf("symlink target = %q, %v, want it left alone", data, err)
+	}
+}
@@ -0,0 +1,400 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	ErrDaemonRunning    = errors.New("a daemon is already listening on the control socket")
+	ErrDaemonNotRunning = errors.New("no daemon is listening on the control socket")
+	ErrUnsafeSocket     = errors.New("control socket is not a socket private to the current user")
+)
+
+// Flags which are passed on to a daemon, or only affect the invocation
+// handing files off to it. Any other flag changes how files are served, so
+// files are served from a new process instead.
+var handOffFlags = map[string]bool{
+	"burn":          true,
+	"config":        true,
+	"count":         true,
+	"expire":        true,
+	"interval":      true,
+	"live-file":     true,
+	"log-format":    true,
+	"log-level":     true,
+	"mail-from":     true,
+	"mail-template": true,
+	"mail-to":       true,
+	"name":          true,
+	"no-daemon":     true,
+	"not-before":    true,
+	"randomize":     true,
+	"smtp-password": true,
+	"smtp-server":   true,
+	"smtp-tls":      true,
+	"smtp-username": true,
+	"socket":        true,
+	"timeout":       true,
+	"yes":           true,
+}
+
+// socketPath returns the path of the control socket, defaulting to one
+// which is private to the current user.
+func socketPath() string {
+	if Socket != "" {
+		return Socket
+	}
+
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir != "" {
+		return filepath.Join(dir, "send.sock")
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("send-%d.sock", os.Getuid()))
+}
+
+// listenControl opens the daemon's control socket, refusing to replace the
+// socket of a daemon which is still running.
+func listenControl(path string) (*Listener, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("%w: %s", ErrDaemonRunning, path)
+	}
+
+	l, err := listenUnix(path, "0600")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Listener{Listener: l, Network: "unix"}, nil
+}
+
+// DaemonClient talks to a running daemon over its control socket.
+type DaemonClient struct {
+	client *http.Client
+}
+
+// dialDaemon connects to the daemon listening on the control socket, if any.
+func dialDaemon(path string) (*DaemonClient, error) {
+	err := checkSocket(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrDaemonNotRunning, path)
+		}
+
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDaemonNotRunning, path)
+	}
+
+	conn.Close()
+
+	dialer := &net.Dialer{}
+
+	return &DaemonClient{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}, nil
+}
+
+// handOff returns the running daemon to which files can be handed off, or
+// nil if they must be served by this process.
+func handOff(flags *pflag.FlagSet) *DaemonClient {
+	client, err := dialDaemon(socketPath())
+	if errors.Is(err, ErrUnsafeSocket) {
+		logEvent(slog.LevelWarn, "daemon_skipped", fmt.Sprintf("Serving from this process: %s", err),
+			"socket", socketPath())
+	}
+	if err != nil {
+		return nil
+	}
+
+	local := ""
+
+	flags.Visit(func(f *pflag.Flag) {
+		if local == "" && !handOffFlags[f.Name] {
+			local = f.Name
+		}
+	})
+
+	if local != "" {
+		logEvent(slog.LevelInfo, "daemon_skipped", fmt.Sprintf("Serving from this process, since --%s is not passed on to the daemon", local),
+			"socket", socketPath(),
+			"flag", local)
+
+		return nil
+	}
+
+	return client
+}
+
+// do sends a request to the daemon's admin API, decoding any JSON response
+// into v.
+func (d *DaemonClient) do(method, path string, body io.Reader, v any) error {
+	req, err := http.NewRequest(method, "http://send"+path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiError := map[string]string{}
+
+		err = json.NewDecoder(resp.Body).Decode(&apiError)
+		if err != nil || apiError["error"] == "" {
+			return fmt.Errorf("daemon responded with %s", resp.Status)
+		}
+
+		return errors.New(apiError["error"])
+	}
+
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func printShareURLs(status *ShareStatus) {
+	for _, f := range status.Files {
+		for _, url := range f.URLs {
+			logEvent(slog.LevelInfo, "share_registered", fmt.Sprintf("%s -> %s", url, f.Path),
+				"slug", status.Slug,
+				"url", url,
+				"path", f.Path,
+				"source", "daemon")
+		}
+	}
+}
+
+// share registers files with the daemon instead of serving them from a new
+// process. Settings which were not given explicitly are left to the daemon.
+func (d *DaemonClient) share(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	// Links are mailed from here rather than by the daemon, since only this
+	// invocation knows who to send them to
+	mailer, err := newMailer(nil)
+	if err != nil {
+		return err
+	}
+
+	config := &ShareConfig{}
+
+	if flags.Changed("count") {
+		config.Count = &Count
+	}
+
+	if flags.Changed("randomize") {
+		config.Randomize = &Randomize
+	}
+
+	if flags.Changed("burn") {
+		config.Burn = &Burn
+	}
+
+	if flags.Changed("live-file") {
+		config.Live = &LiveFile
+	}
+
+	if flags.Changed("expire") {
+		config.Expire = Expire
+	}
+
+	if flags.Changed("not-before") {
+		config.NotBefore = NotBefore
+	}
+
+	// The daemon outlives this invocation, so a timeout becomes an expiry
+	// time for the share, unless it already expires sooner
+	if flags.Changed("timeout") && Timeout > 0 {
+		now := time.Now()
+
+		expire, err := parseTime(config.Expire, now)
+		if err != nil {
+			return err
+		}
+
+		if expire.IsZero() || now.Add(Timeout).Before(expire) {
+			expire = now.Add(Timeout)
+		}
+
+		config.Expire = expire.Format(time.RFC3339)
+	}
+
+	if isFromPipe() {
+		content, err := readStdin()
+		if err != nil {
+			return err
+		}
+
+		query := url.Values{}
+
+		if config.Count != nil {
+			query.Set("count", strconv.Itoa(*config.Count))
+		}
+
+		if config.Burn != nil {
+			query.Set("burn", strconv.FormatBool(*config.Burn))
+		}
+
+		query.Set("expire", config.Expire)
+		query.Set("not_before", config.NotBefore)
+
+		if Name != "" && !Randomize {
+			query.Set("name", Name)
+		}
+
+		status := &ShareStatus{}
+
+		err = d.do(http.MethodPost, "/blobs?"+query.Encode(), bytes.NewReader(content), status)
+		if err != nil {
+			return err
+		}
+
+		printShareURLs(status)
+
+		err = mailer.mailShare(status)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	var burning []string
+
+	for _, arg := range args {
+		// Objects are fetched by the daemon, with its own credentials, and
+		// never burned
+		if isS3URL(arg) {
+			config.Paths = append(config.Paths, arg)
+
+			continue
+		}
+
+		path, err := filepath.Abs(arg)
+		if err != nil {
+			return err
+		}
+
+		config.Paths = append(config.Paths, path)
+
+		if Burn {
+			burning = append(burning, path)
+		}
+	}
+
+	// Burning is confirmed before the daemon is asked to share anything, so
+	// that its links are never served or announced if it is cancelled
+	err = confirmBurn(burning, nil)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	status := &ShareStatus{}
+
+	err = d.do(http.MethodPost, "/shares", bytes.NewReader(body), status)
+	if err != nil {
+		return err
+	}
+
+	printShareURLs(status)
+
+	return mailer.mailShare(status)
+}
+
+func (d *DaemonClient) list() error {
+	var statuses []ShareStatus
+
+	err := d.do(http.MethodGet, "/shares", nil, &statuses)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "SLUG\tSTATUS\tDOWNLOADS\tEXPIRES\tURL")
+
+	for _, s := range statuses {
+		downloads := strconv.Itoa(s.Served)
+		if s.Count != 0 {
+			downloads = fmt.Sprintf("%d/%d", s.Served, s.Count)
+		}
+
+		expires := "never"
+		if !s.Expire.IsZero() {
+			expires = s.Expire.Local().Format(logDate)
+		}
+
+		for _, f := range s.Files {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				s.Slug,
+				s.Status,
+				downloads,
+				expires,
+				strings.Join(f.URLs, " "))
+		}
+	}
+
+	return w.Flush()
+}
+
+func (d *DaemonClient) remove(slugs []string) error {
+	for _, slug := range slugs {
+		slug = strings.Trim(slug, "/")
+
+		err := d.do(http.MethodDelete, "/shares/"+url.PathEscape(slug), nil, nil)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Revoked %s\n", slug)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkSocket refuses a control socket which another user created or could
+// replace, since files handed off through it would be served by them.
+func checkSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+
+	switch {
+	case info.Mode().Type() != os.ModeSocket,
+		!ok || int(stat.Uid) != os.Getuid(),
+		info.Mode().Perm()&0o022 != 0:
+		return fmt.Errorf("%w: %s", ErrUnsafeSocket, path)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSocket(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "send.sock")
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	file := filepath.Join(dir, "file")
+
+	err = os.WriteFile(file, nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		mode os.FileMode
+		err  error
+	}{
+		{path, 0600, nil},
+		{path, 0700, nil},
+		{path, 0620, ErrUnsafeSocket},
+		{path, 0602, ErrUnsafeSocket},
+		{file, 0600, ErrUnsafeSocket},
+		{filepath.Join(dir, "missing"), 0, os.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		if tt.mode != 0 {
+			err = os.Chmod(tt.path, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err = checkSocket(tt.path)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("checkSocket(%s, %v) = %v, want %v", filepath.Base(tt.path), tt.mode, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"os"
+)
+
+// checkSocket only checks that the control socket exists, since access to it
+// is governed by the ACL of its directory.
+func checkSocket(path string) error {
+	_, err := os.Lstat(path)
+
+	return err
+}
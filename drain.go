@@ -0,0 +1,210 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Transfer is a single download in progress.
+type Transfer struct {
+	Path    string
+	Client  string
+	Size    int64
+	Started time.Time
+
+	w       io.Writer
+	written atomic.Int64
+}
+
+func (t *Transfer) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+
+	t.written.Add(int64(n))
+
+	return n, err
+}
+
+func (t *Transfer) Written() int64 {
+	return t.written.Load()
+}
+
+// attrs returns the fields describing the transfer in structured logs.
+func (t *Transfer) attrs() []any {
+	return []any{
+		"path", t.Path,
+		"client", t.Client,
+		"bytes", t.Written(),
+		"size", t.Size,
+		"duration_seconds", time.Since(t.Started).Seconds(),
+	}
+}
+
+func (t *Transfer) progress() string {
+	written := t.Written()
+
+	if t.Size <= 0 {
+		return formatBytes(float64(written))
+	}
+
+	return fmt.Sprintf("%s of %s, %d%%",
+		formatBytes(float64(written)),
+		formatBytes(float64(t.Size)),
+		written*100/t.Size)
+}
+
+// Transfers keeps track of downloads in progress, and of how finished ones
+// ended, so that a summary can be given on shutdown.
+type Transfers struct {
+	mu        sync.Mutex
+	active    map[*Transfer]struct{}
+	completed []*Transfer
+	aborted   []*Transfer
+	closed    bool
+}
+
+func newTransfers() *Transfers {
+	return &Transfers{
+		active: make(map[*Transfer]struct{}),
+	}
+}
+
+func (t *Transfers) begin(path, client string, size int64, w io.Writer) *Transfer {
+	transfer := &Transfer{
+		Path:    path,
+		Client:  client,
+		Size:    size,
+		Started: time.Now(),
+		w:       w,
+	}
+
+	t.mu.Lock()
+	t.active[transfer] = struct{}{}
+	t.mu.Unlock()
+
+	return transfer
+}
+
+func (t *Transfers) end(transfer *Transfer, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return
+	}
+
+	delete(t.active, transfer)
+
+	if err != nil {
+		t.aborted = append(t.aborted, transfer)
+	} else {
+		t.completed = append(t.completed, transfer)
+	}
+}
+
+func (t *Transfers) list() []*Transfer {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	transfers := make([]*Transfer, 0, len(t.active))
+
+	for transfer := range t.active {
+		transfers = append(transfers, transfer)
+	}
+
+	return transfers
+}
+
+// close stops recording results, counting anything still active as aborted.
+func (t *Transfers) close() (completed, aborted []*Transfer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+
+	for transfer := range t.active {
+		t.aborted = append(t.aborted, transfer)
+	}
+
+	clear(t.active)
+
+	return t.completed, t.aborted
+}
+
+// drainHandler refuses new requests once shutdown has begun.
+func drainHandler(next http.Handler, limits *Limits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limits.draining.Load() {
+			w.Header().Set("Connection", "close")
+
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// drain refuses new requests, then waits up to DrainTimeout for active
+// transfers to finish before closing the server.
+func drain(srv *http.Server, limits *Limits, reason string) error {
+	limits.draining.Store(true)
+
+	logEvent(slog.LevelInfo, "draining", fmt.Sprintf("%s, draining...", reason),
+		"reason", reason)
+
+	for _, transfer := range limits.transfers.list() {
+		logEvent(slog.LevelInfo, "download_in_progress",
+			fmt.Sprintf("In progress: %s => %s (%s)", transfer.Path, transfer.Client, transfer.progress()),
+			transfer.attrs()...)
+	}
+
+	ctx := context.Background()
+
+	if DrainTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, DrainTimeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for len(limits.transfers.list()) > 0 && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		err = srv.Close()
+	}
+
+	completed, aborted := limits.transfers.close()
+
+	for _, transfer := range aborted {
+		logEvent(slog.LevelWarn, "download_aborted",
+			fmt.Sprintf("Aborted: %s => %s (%s)", transfer.Path, transfer.Client, transfer.progress()),
+			transfer.attrs()...)
+	}
+
+	logEvent(slog.LevelInfo, "drained",
+		fmt.Sprintf("%d transfer(s) completed, %d aborted", len(completed), len(aborted)),
+		"completed", len(completed),
+		"aborted", len(aborted))
+
+	return err
+}
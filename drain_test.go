@@ -0,0 +1,116 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDrainHandler(t *testing.T) {
+	limits := newTestLimits()
+
+	handler := drainHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), limits)
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status before draining = %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	limits.draining.Store(true)
+
+	w = httptest.NewRecorder()
+
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Connection") != "close" {
+		t.Errorf("status while draining = %d (Connection: %q), want %d and the connection closed",
+			w.Code, w.Header().Get("Connection"), http.StatusServiceUnavailable)
+	}
+}
+
+func TestTransfers(t *testing.T) {
+	transfers := newTransfers()
+
+	completed := transfers.begin("/a", "client", 1, io.Discard)
+	failed := transfers.begin("/b", "client", 1, io.Discard)
+	active := transfers.begin("/c", "client", 1, io.Discard)
+
+	transfers.end(completed, nil)
+	transfers.end(failed, errors.New("connection reset"))
+
+	if list := transfers.list(); len(list) != 1 || list[0] != active {
+		t.Errorf("active = %v, want only /c", list)
+	}
+
+	done, aborted := transfers.close()
+	if len(done) != 1 || len(aborted) != 2 {
+		t.Errorf("close = %d completed, %d aborted, want 1 and 2", len(done), len(aborted))
+	}
+
+	// A transfer finishing after the summary stays counted as aborted
+	transfers.end(active, nil)
+
+	done, aborted = transfers.close()
+	if len(done) != 1 || len(aborted) != 2 {
+		t.Errorf("close again = %d completed, %d aborted, want 1 and 2", len(done), len(aborted))
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		finish    time.Duration
+		completed int
+		aborted   int
+	}{
+		{"finishes in time", 5 * time.Second, 200 * time.Millisecond, 1, 0},
+		{"times out", 200 * time.Millisecond, time.Hour, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFlag(t, &DrainTimeout, tt.timeout)
+
+			limits := newTestLimits()
+
+			transfer := limits.transfers.begin("/a", "client", 1, io.Discard)
+
+			timer := time.AfterFunc(tt.finish, func() { limits.transfers.end(transfer, nil) })
+			defer timer.Stop()
+
+			started := time.Now()
+
+			err := drain(&http.Server{}, limits, "Interrupted")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			elapsed := time.Since(started)
+			if elapsed < min(tt.timeout, tt.finish) || elapsed > 2*time.Second {
+				t.Errorf("drain took %s", elapsed)
+			}
+
+			if !limits.draining.Load() {
+				t.Error("new requests were not refused while draining")
+			}
+
+			completed, aborted := limits.transfers.close()
+			if len(completed) != tt.completed || len(aborted) != tt.aborted {
+				t.Errorf("drain = %d completed, %d aborted, want %d and %d", len(completed), len(aborted), tt.completed, tt.aborted)
+			}
+		})
+	}
+}
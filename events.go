@@ -0,0 +1,67 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Event is something that happened to a share or to the server, as passed to
+// the audit log and other consumers.
+type Event struct {
+	Type      string    `json:"event"`
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source,omitempty"`
+	Slug      string    `json:"slug,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Paths     []string  `json:"paths,omitempty"`
+	URLs      []string  `json:"urls,omitempty"`
+	Client    string    `json:"client,omitempty"`
+	Bytes     int64     `json:"bytes,omitempty"`
+	Duration  float64   `json:"duration_seconds,omitempty"`
+	Count     int       `json:"count,omitempty"`
+	Remaining *int      `json:"remaining,omitempty"`
+	Expire    time.Time `json:"expire,omitzero"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// shareEvent describes a share and all of its files.
+func shareEvent(kind, source string, s *Share, bases []string) *Event {
+	count, _ := s.counts()
+
+	event := &Event{
+		Type:   kind,
+		Source: source,
+		Slug:   s.Slug,
+		Count:  count,
+		Expire: s.expiry(),
+	}
+
+	for _, f := range s.registry.files(s) {
+		event.Paths = append(event.Paths, f.Path)
+		event.URLs = append(event.URLs, fileURLs(bases, s, f)...)
+	}
+
+	return event
+}
+
+// emit passes an event to everything consuming them.
+func (l *Limits) emit(e *Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	// Slugs are given without their leading slash, as in the admin API
+	e.Slug = strings.TrimPrefix(e.Slug, "/")
+
+	l.audit.record(e)
+
+	l.hooks.run(e)
+
+	l.mailer.notifyDownload(e)
+
+	l.webhooks.send(e)
+}
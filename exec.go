@@ -0,0 +1,212 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// Trailer reporting whether the command generating a response succeeded
+	commandStatusTrailer = "X-Send-Command-Status"
+)
+
+var (
+	ErrCommandFailed     = errors.New("command failed")
+	ErrInvalidExec       = errors.New("exec concurrency and timeout must be non-negative")
+	ErrResponseTruncated = errors.New("response truncated")
+)
+
+// Runner limits how many commands generating responses run at once.
+type Runner struct {
+	slots chan struct{}
+}
+
+// newRunner returns nil, allowing any number of commands, if concurrency is
+// zero.
+func newRunner(concurrency int) *Runner {
+	if concurrency == 0 {
+		return nil
+	}
+
+	return &Runner{slots: make(chan struct{}, concurrency)}
+}
+
+// acquire claims a slot for a command, failing if all are in use.
+func (r *Runner) acquire() bool {
+	if r == nil {
+		return true
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *Runner) release() {
+	if r == nil {
+		return
+	}
+
+	<-r.slots
+}
+
+// commandPath describes a command's output in place of a file path.
+func commandPath(command string) string {
+	return fmt.Sprintf("<output of %s>", command)
+}
+
+// registerCommand publishes a file whose content is generated by running
+// command on every request.
+func registerCommand(registry *Registry, command string, share *Share, bases []string) (urls []string, path string, err error) {
+	file := &File{
+		Name:    commandName(share),
+		Path:    commandPath(command),
+		command: command,
+	}
+
+	urls, err = publishFile(registry, share, file, bases)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return urls, file.Path, nil
+}
+
+// commandName returns the filename component of the URL for generated
+// content, which is random unless set with --name.
+func commandName(share *Share) string {
+	if Name != "" && !share.Randomize {
+		return "/" + Name
+	}
+
+	return fileName(share, "")
+}
+
+// startCommand runs command, returning its output as it is produced. Reading
+// past the end of the output returns an error if the command failed.
+func startCommand(ctx context.Context, command string) (io.ReadCloser, error) {
+	var cancel context.CancelFunc
+
+	if ExecTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, ExecTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	cmd := shellCommand(ctx, command)
+
+	cmd.WaitDelay = time.Second
+
+	r := &commandReader{cmd: cmd, ctx: ctx, cancel: cancel}
+
+	cmd.Stderr = &r.stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	r.stdout = stdout
+
+	return r, nil
+}
+
+type commandReader struct {
+	cmd    *exec.Cmd
+	ctx    context.Context
+	cancel context.CancelFunc
+	stdout io.Reader
+	stderr bytes.Buffer
+	waited bool
+}
+
+func (r *commandReader) Read(p []byte) (int, error) {
+	n, err := r.stdout.Read(p)
+	if !errors.Is(err, io.EOF) {
+		return n, err
+	}
+
+	err = r.wait()
+	if err != nil {
+		return n, err
+	}
+
+	return n, io.EOF
+}
+
+// wait collects the exit status of the command once its output has been
+// read.
+func (r *commandReader) wait() error {
+	r.waited = true
+
+	err := r.cmd.Wait()
+	if errors.Is(r.ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", ExecTimeout)
+	}
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrCommandFailed, withStderr(err, r.stderr.String()))
+}
+
+// Close kills the command if it is still running, as when the client has
+// gone away.
+func (r *commandReader) Close() error {
+	r.cancel()
+
+	if !r.waited {
+		r.waited = true
+
+		r.cmd.Wait()
+	}
+
+	return nil
+}
+
+// commandStatus is the value of the trailer reporting how a command ended.
+// It gives at most the exit status, since the command's stderr output is
+// only meant for the server's own log.
+func commandStatus(err error) string {
+	var exitErr *exec.ExitError
+
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		return fmt.Sprintf("failed (exit status %d)", exitErr.ExitCode())
+	default:
+		return "failed"
+	}
+}
+
+// acceptsTrailers reports whether the client has said it can handle
+// trailers, so will see a failure reported in one.
+func acceptsTrailers(r *http.Request) bool {
+	for value := range strings.SplitSeq(r.Header.Get("TE"), ",") {
+		if strings.EqualFold(strings.TrimSpace(value), "trailers") {
+			return true
+		}
+	}
+
+	return false
+}
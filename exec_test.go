@@ -0,0 +1,51 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCommandStatus(t *testing.T) {
+	tests := []struct {
+		command string
+		output  string
+		status  string
+	}{
+		{"echo hello", "hello\n", "ok"},
+		{"echo partial; echo secret >&2; exit 3", "partial\n", "failed (exit status 3)"},
+	}
+
+	for _, tt := range tests {
+		r, err := startCommand(context.Background(), tt.command)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		output, err := io.ReadAll(r)
+
+		r.Close()
+
+		if string(output) != tt.output {
+			t.Errorf("%s: output = %q, want %q", tt.command, output, tt.output)
+		}
+
+		if tt.status != "ok" && !errors.Is(err, ErrCommandFailed) {
+			t.Errorf("%s: error = %v, want %v", tt.command, err, ErrCommandFailed)
+		}
+
+		status := commandStatus(err)
+		if status != tt.status {
+			t.Errorf("%s: status = %q, want %q", tt.command, status, tt.status)
+		}
+	}
+
+	if status := commandStatus(context.Canceled); status != "failed" {
+		t.Errorf("status = %q, want failed", status)
+	}
+}
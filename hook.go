@@ -0,0 +1,148 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Most stderr output from a failed command included in its error
+	hookStderrLimit = 4096
+)
+
+var (
+	ErrInvalidHookTimeout = errors.New("hook timeout must be a non-negative duration")
+)
+
+// Hooks runs commands in response to events, each in the background with
+// details of the event in its environment.
+type Hooks struct {
+	download  string
+	exhausted string
+	timeout   time.Duration
+	running   sync.WaitGroup
+
+	errorChannel chan<- Error
+}
+
+func newHooks(download, exhausted string, timeout time.Duration, errorChannel chan<- Error) *Hooks {
+	if download == "" && exhausted == "" {
+		return nil
+	}
+
+	return &Hooks{
+		download:     download,
+		exhausted:    exhausted,
+		timeout:      timeout,
+		errorChannel: errorChannel,
+	}
+}
+
+// run starts the hook for an event, if there is one.
+func (h *Hooks) run(e *Event) {
+	if h == nil {
+		return
+	}
+
+	var name, command string
+
+	switch e.Type {
+	case "download_completed":
+		name, command = "on-download", h.download
+	case "share_exhausted":
+		name, command = "on-exhausted", h.exhausted
+	}
+
+	if command == "" {
+		return
+	}
+
+	h.running.Add(1)
+
+	go func() {
+		defer h.running.Done()
+
+		err := h.exec(command, e)
+		if err != nil {
+			h.errorChannel <- Error{Message: fmt.Errorf("%s hook: %w", name, err)}
+		}
+	}()
+}
+
+func (h *Hooks) exec(command string, e *Event) error {
+	ctx := context.Background()
+
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	cmd := shellCommand(ctx, command)
+
+	remaining := ""
+	if e.Remaining != nil {
+		remaining = strconv.Itoa(*e.Remaining)
+	}
+
+	cmd.Env = append(os.Environ(),
+		"SEND_EVENT="+e.Type,
+		"SEND_SLUG="+e.Slug,
+		"SEND_PATH="+e.Path,
+		"SEND_CLIENT="+e.Client,
+		"SEND_BYTES="+strconv.FormatInt(e.Bytes, 10),
+		"SEND_REMAINING="+remaining)
+
+	var stderr bytes.Buffer
+
+	cmd.Stderr = &stderr
+
+	// Stop waiting for output once the command exits, even if it left
+	// behind children still holding stderr open
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", h.timeout)
+	}
+	if err == nil {
+		return nil
+	}
+
+	return withStderr(err, stderr.String())
+}
+
+// withStderr adds what a failed command wrote to stderr to its error.
+func withStderr(err error, stderr string) error {
+	output := strings.TrimSpace(stderr)
+	if len(output) > hookStderrLimit {
+		output = output[:hookStderrLimit] + "..."
+	}
+
+	if output != "" {
+		return fmt.Errorf("%w: %s", err, output)
+	}
+
+	return err
+}
+
+// Wait blocks until every running hook has finished.
+func (h *Hooks) Wait() {
+	if h == nil {
+		return
+	}
+
+	h.running.Wait()
+}
@@ -0,0 +1,86 @@
+//go:build !windows
+
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+
+	errorChannel := make(chan Error, 10)
+
+	hooks := newHooks(
+		`printf '%s|%s|%s|%s|%s|%s' "$SEND_EVENT" "$SEND_SLUG" "$SEND_PATH" "$SEND_CLIENT" "$SEND_BYTES" "$SEND_REMAINING" > `+out,
+		`echo "used up $SEND_SLUG" >&2; exit 2`,
+		5*time.Second, errorChannel)
+
+	remaining := 2
+
+	hooks.run(&Event{Type: "download_completed", Slug: "abc", Path: "/srv/a.txt", Client: "192.0.2.1", Bytes: 42, Remaining: &remaining})
+	hooks.run(&Event{Type: "share_exhausted", Slug: "abc"})
+	hooks.run(&Event{Type: "share_created", Slug: "abc"})
+
+	hooks.Wait()
+
+	env, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "download_completed|abc|/srv/a.txt|192.0.2.1|42|2"
+	if string(env) != want {
+		t.Errorf("hook environment = %q, want %q", env, want)
+	}
+
+	select {
+	case err := <-errorChannel:
+		for _, part := range []string{"on-exhausted hook", "exit status 2", "used up abc"} {
+			if !strings.Contains(err.Message.Error(), part) {
+				t.Errorf("error = %q, want it to include %q", err.Message, part)
+			}
+		}
+	default:
+		t.Error("failed hook was not reported")
+	}
+
+	select {
+	case err := <-errorChannel:
+		t.Errorf("unexpected error: %v", err.Message)
+	default:
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	errorChannel := make(chan Error, 1)
+
+	hooks := newHooks("sleep 10", "", 100*time.Millisecond, errorChannel)
+
+	started := time.Now()
+
+	hooks.run(&Event{Type: "download_completed"})
+
+	hooks.Wait()
+
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("hook ran for %s, want it killed after %s", elapsed, hooks.timeout)
+	}
+
+	select {
+	case err := <-errorChannel:
+		if !strings.Contains(err.Message.Error(), "timed out after 100ms") {
+			t.Errorf("error = %q, want a timeout", err.Message)
+		}
+	default:
+		t.Error("timed out hook was not reported")
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// IdleTimer fires once no download has been in progress for the configured
+// duration.
+type IdleTimer struct {
+	mu       sync.Mutex
+	duration time.Duration
+	deadline time.Time
+	timer    *time.Timer
+	active   int
+}
+
+func newIdleTimer(d time.Duration, f func()) *IdleTimer {
+	return &IdleTimer{
+		duration: d,
+		deadline: time.Now().Add(d),
+		timer:    time.AfterFunc(d, f),
+	}
+}
+
+// Pause stops the countdown while a download is in progress. It is safe to
+// call on a nil timer, which is used when no idle timeout is configured.
+func (t *IdleTimer) Pause() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active++
+
+	t.timer.Stop()
+}
+
+// Resume restarts the countdown once the last download in progress has
+// ended.
+func (t *IdleTimer) Resume() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active--
+
+	if t.active == 0 {
+		t.deadline = time.Now().Add(t.duration)
+		t.timer.Reset(t.duration)
+	}
+}
+
+// Remaining returns the time left before the timer fires, which is zero
+// while it is paused.
+func (t *IdleTimer) Remaining() time.Duration {
+	if t == nil {
+		return 0
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.active > 0 {
+		return 0
+	}
+
+	return time.Until(t.deadline)
+}
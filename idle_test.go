@@ -0,0 +1,35 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdleTimerPausesDuringDownloads(t *testing.T) {
+	fired := make(chan struct{}, 1)
+
+	timer := newIdleTimer(50*time.Millisecond, func() { fired <- struct{}{} })
+
+	timer.Pause()
+	timer.Pause()
+
+	timer.Resume()
+
+	select {
+	case <-fired:
+		t.Fatal("timer fired while a download was in progress")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	timer.Resume()
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire after the last download ended")
+	}
+}
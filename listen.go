@@ -0,0 +1,287 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidListen = errors.New("listen address must be of the form tcp://host:port, unix:///path or fd://<number|name>, optionally with +tls appended to the scheme")
+	ErrNoSystemdFD   = errors.New("no matching socket was passed in by systemd")
+)
+
+const (
+	// First file descriptor passed in by systemd socket activation
+	listenFDStart = 3
+)
+
+// Listener is a socket on which send serves requests, over either HTTP or
+// HTTPS independently of any other listeners.
+type Listener struct {
+	net.Listener
+
+	Network string
+	TLS     bool
+}
+
+func (l *Listener) Scheme() string {
+	if l.TLS {
+		return "https"
+	}
+
+	return "http"
+}
+
+func (l *Listener) String() string {
+	if l.Network == "unix" {
+		return fmt.Sprintf("%s+unix:%s", l.Scheme(), l.Addr())
+	}
+
+	return fmt.Sprintf("%s://%s/", l.Scheme(), l.Addr())
+}
+
+// systemdListeners returns the sockets passed in through socket activation,
+// keyed by both their index and any name given in LISTEN_FDNAMES. The
+// environment is only read once, as it is cleared afterwards.
+var systemdListeners = sync.OnceValues(readSystemdListeners)
+
+func readSystemdListeners() (map[string]*os.File, error) {
+	files := make(map[string]*os.File)
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return files, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	for i := range count {
+		fd := listenFDStart + i
+
+		f := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+
+		files[strconv.Itoa(fd)] = f
+
+		if i < len(names) && names[i] != "" {
+			files[names[i]] = f
+		}
+	}
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	return files, nil
+}
+
+func listenUnix(path, mode string) (net.Listener, error) {
+	f, err := os.Lstat(path)
+	if err == nil && f.Mode()&os.ModeSocket != 0 {
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != "" {
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil {
+			l.Close()
+
+			return nil, fmt.Errorf("%w: invalid mode %q", ErrInvalidListen, mode)
+		}
+
+		err = os.Chmod(path, os.FileMode(perm))
+		if err != nil {
+			l.Close()
+
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// parsePortRange accepts a single port, "auto" (equivalent to 0, letting the
+// kernel pick a free port), or an inclusive range such as "8080-8099".
+func parsePortRange(s string) (first, last int, err error) {
+	if s == "auto" {
+		return 0, 0, nil
+	}
+
+	from, to, isRange := strings.Cut(s, "-")
+
+	first, err = strconv.Atoi(from)
+	if err != nil || first < 0 || first > 65535 {
+		return 0, 0, ErrInvalidPort
+	}
+
+	if !isRange {
+		return first, first, nil
+	}
+
+	last, err = strconv.Atoi(to)
+	if err != nil || first < 1 || last < first || last > 65535 {
+		return 0, 0, ErrInvalidPort
+	}
+
+	return first, last, nil
+}
+
+func isValidPort(s string) bool {
+	_, _, err := parsePortRange(s)
+
+	return err == nil
+}
+
+// listenTCP listens on the first free port in the range given by addr.
+func listenTCP(addr string) (net.Listener, error) {
+	host, ports, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidListen, addr)
+	}
+
+	first, last, err := parsePortRange(ports)
+	if err != nil {
+		return nil, err
+	}
+
+	for port := first; ; port++ {
+		l, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err == nil {
+			return l, nil
+		}
+
+		if port >= last {
+			if first != last {
+				return nil, fmt.Errorf("no free port between %d and %d: %w", first, last, err)
+			}
+
+			return nil, err
+		}
+	}
+}
+
+func openListener(spec string, systemd map[string]*os.File) (*Listener, error) {
+	scheme, addr, ok := strings.Cut(spec, "://")
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidListen, spec)
+	}
+
+	scheme, secure := strings.CutSuffix(scheme, "+tls")
+
+	listener := &Listener{TLS: secure}
+
+	var err error
+
+	switch scheme {
+	case "tcp", "http", "https":
+		listener.Network = "tcp"
+		listener.TLS = secure || scheme == "https"
+
+		listener.Listener, err = listenTCP(strings.TrimSuffix(addr, "/"))
+	case "unix":
+		u, err := url.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidListen, spec)
+		}
+
+		listener.Network = "unix"
+
+		listener.Listener, err = listenUnix(u.Path, u.Query().Get("mode"))
+		if err != nil {
+			return nil, err
+		}
+	case "fd":
+		f, ok := systemd[addr]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNoSystemdFD, spec)
+		}
+
+		listener.Listener, err = net.FileListener(f)
+		if err == nil {
+			listener.Network = listener.Addr().Network()
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidListen, spec)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// openListeners binds every requested address, or Bind and Port if none
+// were given. On error, any listeners already opened are closed again.
+func openListeners(specs []string) ([]*Listener, error) {
+	if len(specs) == 0 {
+		l, err := listenTCP(net.JoinHostPort(Bind, Port))
+		if err != nil {
+			return nil, err
+		}
+
+		return []*Listener{{Listener: l, Network: "tcp", TLS: TLSCert != ""}}, nil
+	}
+
+	systemd, err := systemdListeners()
+	if err != nil {
+		return nil, err
+	}
+
+	listeners := make([]*Listener, 0, len(specs))
+
+	for _, spec := range specs {
+		l, err := openListener(spec, systemd)
+		if err != nil {
+			closeListeners(listeners)
+
+			return nil, err
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// listenersNeedTLS reports whether any of the given listen addresses asks
+// for HTTPS.
+func listenersNeedTLS(specs []string) bool {
+	for _, spec := range specs {
+		scheme, _, _ := strings.Cut(spec, "://")
+
+		if strings.HasSuffix(scheme, "+tls") || scheme == "https" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func closeListeners(listeners []*Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
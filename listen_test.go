@@ -0,0 +1,48 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	tests := []struct {
+		in          string
+		first, last int
+		err         error
+	}{
+		{"auto", 0, 0, nil},
+		{"0", 0, 0, nil},
+		{"8080", 8080, 8080, nil},
+		{"65535", 65535, 65535, nil},
+		{"8080-8090", 8080, 8090, nil},
+		{"8080-8080", 8080, 8080, nil},
+		{"1-65535", 1, 65535, nil},
+		{"", 0, 0, ErrInvalidPort},
+		{"http", 0, 0, ErrInvalidPort},
+		{"-1", 0, 0, ErrInvalidPort},
+		{"65536", 0, 0, ErrInvalidPort},
+		{"0-10", 0, 0, ErrInvalidPort},
+		{"8090-8080", 0, 0, ErrInvalidPort},
+		{"8080-65536", 0, 0, ErrInvalidPort},
+		{"8080-", 0, 0, ErrInvalidPort},
+		{"8080-8090-8100", 0, 0, ErrInvalidPort},
+	}
+
+	for _, tt := range tests {
+		first, last, err := parsePortRange(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parsePortRange(%q) error = %v, want %v", tt.in, err, tt.err)
+
+			continue
+		}
+
+		if first != tt.first || last != tt.last {
+			t.Errorf("parsePortRange(%q) = %d, %d, want %d, %d", tt.in, first, last, tt.first, tt.last)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// How often a followed file is checked for new data
+	liveFollowPoll = 250 * time.Millisecond
+)
+
+var (
+	ErrInvalidLive     = errors.New("live file settle and follow times must be non-negative durations")
+	ErrLiveFollowStall = errors.New("live file follow time must be shorter than the stall timeout, which would otherwise drop followed downloads")
+)
+
+// liveETag identifies a version of a live file by its modification time and
+// size.
+func liveETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
+
+// matchETag reports whether an If-None-Match header includes etag.
+func matchETag(header, etag string) bool {
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// liveHeader returns the validators of a live file's current version.
+func liveHeader(info os.FileInfo) http.Header {
+	header := make(http.Header)
+
+	header.Set("ETag", liveETag(info))
+	header.Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+
+	return header
+}
+
+// checkLive answers requests for a live file which need no download: those
+// for the version the client already has, and, with --live-settle, those made
+// while the file is still being written. It reports whether the request has
+// been answered. A download gets its validators from openLive instead, so
+// that they describe the version actually sent.
+func checkLive(w http.ResponseWriter, r *http.Request, file *File) (bool, error) {
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		http.NotFound(w, r)
+
+		return true, err
+	}
+
+	if age := time.Since(info.ModTime()); age < LiveSettle {
+		logEvent(slog.LevelInfo, "download_deferred", fmt.Sprintf("Not serving %s, since it was modified %s ago", file.Path, age.Round(time.Millisecond)),
+			"path", file.Path,
+			"client", loggedIP(realIP(r, true)))
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil((LiveSettle - age).Seconds()))))
+
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+		return true, nil
+	}
+
+	if matchETag(r.Header.Get("If-None-Match"), liveETag(info)) {
+		for key, values := range liveHeader(info) {
+			w.Header()[key] = values
+		}
+
+		w.WriteHeader(http.StatusNotModified)
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// openLive opens the current content of a live file. With --live-follow, the
+// content includes anything appended while it is being sent, so its size is
+// not known in advance.
+func openLive(ctx context.Context, file *File, flush func()) (*Body, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return nil, err
+	}
+
+	if LiveFollow > 0 {
+		return &Body{ReadCloser: &followReader{f: f, ctx: ctx, idle: LiveFollow, flush: flush, last: time.Now()}, Size: -1, Header: liveHeader(info)}, nil
+	}
+
+	// Anything appended after the file was opened is left for the next
+	// request, so the response matches its Content-Length
+	return &Body{ReadCloser: struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(f, info.Size()), f}, Size: info.Size(), Header: liveHeader(info)}, nil
+}
+
+// followReader reads a file as it grows, like tail -f, until no data has
+// been appended for the idle time or the client goes away.
+type followReader struct {
+	f     *os.File
+	ctx   context.Context
+	idle  time.Duration
+	flush func()
+	last  time.Time
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	flushed := false
+
+	for {
+		n, err := r.f.Read(p)
+		if n > 0 || !errors.Is(err, io.EOF) {
+			r.last = time.Now()
+
+			return n, err
+		}
+
+		if time.Since(r.last) >= r.idle {
+			return 0, io.EOF
+		}
+
+		// Send what has been read so far before waiting for more
+		if !flushed {
+			r.flush()
+
+			flushed = true
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		case <-time.After(liveFollowPoll):
+		}
+	}
+}
+
+func (r *followReader) Close() error {
+	return r.f.Close()
+}
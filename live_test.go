@@ -0,0 +1,197 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func addLiveFile(t *testing.T, registry *Registry, share *Share, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "live.log")
+
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = registry.add(share)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = registry.addFile(share, &File{Name: "/live.log", Path: path, live: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLiveETag(t *testing.T) {
+	setFlag(t, &LiveFollow, 0)
+	setFlag(t, &LiveSettle, 0)
+
+	registry := newRegistry(true)
+
+	share := &Share{Slug: "/test", Live: true}
+
+	path := addLiveFile(t, registry, share, "first")
+
+	limits := newTestLimits()
+
+	w := serveTest(t, registry, limits, httptest.NewRequest(http.MethodGet, "/test/live.log", nil))
+
+	etag := w.Header().Get("ETag")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusOK || w.Body.String() != "first" || etag != liveETag(info) {
+		t.Fatalf("first download = %d %q with ETag %q, want %q", w.Code, w.Body, etag, liveETag(info))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/test/live.log", nil)
+	r.Header.Set("If-None-Match", `"other", `+etag)
+
+	w = serveTest(t, registry, limits, r)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+		t.Errorf("unchanged download = %d %q with ETag %q, want 304", w.Code, w.Body, w.Header().Get("ETag"))
+	}
+
+	if _, served := share.counts(); served != 1 {
+		t.Errorf("served = %d, want the 304 not to be counted", served)
+	}
+
+	err = os.WriteFile(path, []byte("second version"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/test/live.log", nil)
+	r.Header.Set("If-None-Match", etag)
+
+	w = serveTest(t, registry, limits, r)
+	if w.Code != http.StatusOK || w.Body.String() != "second version" || w.Header().Get("ETag") == etag {
+		t.Errorf("changed download = %d %q with ETag %q, want the new version", w.Code, w.Body, w.Header().Get("ETag"))
+	}
+}
+
+func TestFollowReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "live.log")
+
+	err := os.WriteFile(path, []byte("first\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flushes := 0
+
+	r := &followReader{f: f, ctx: context.Background(), idle: 3 * liveFollowPoll, flush: func() { flushes++ }, last: time.Now()}
+	defer r.Close()
+
+	go func() {
+		time.Sleep(liveFollowPoll / 2)
+
+		appended, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return
+		}
+		defer appended.Close()
+
+		appended.Write([]byte("second\n"))
+	}()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("followed content = %q, want the appended line included", data)
+	}
+
+	if flushes == 0 {
+		t.Error("content was not flushed while waiting for more")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancel()
+
+	r.ctx, r.last = ctx, time.Now()
+
+	_, err = r.Read(make([]byte, 16))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Read after the client went away = %v, want %v", err, context.Canceled)
+	}
+}
+
+// TestLiveFollowWithinStallTimeout checks that a followed download which
+// goes quiet for less than the follow time, as ErrLiveFollowStall requires
+// to be shorter than the stall timeout, is not dropped as stalled.
+func TestLiveFollowWithinStallTimeout(t *testing.T) {
+	setFlag(t, &LiveFollow, 3*liveFollowPoll)
+	setFlag(t, &LiveSettle, 0)
+	setFlag(t, &StallTimeout, 4*liveFollowPoll)
+
+	registry := newRegistry(true)
+
+	path := addLiveFile(t, registry, &Share{Slug: "/test", Live: true}, "first\n")
+
+	errorChannel := make(chan Error, 16)
+
+	server := httptest.NewServer(shareHandler(registry, newTestLimits(), errorChannel))
+	defer server.Close()
+
+	go func() {
+		time.Sleep(2 * liveFollowPoll)
+
+		appended, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return
+		}
+		defer appended.Close()
+
+		appended.Write([]byte("second\n"))
+	}()
+
+	resp, err := http.Get(server.URL + "/test/live.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("followed download = %q, want the appended line included", data)
+	}
+
+	select {
+	case err := <-errorChannel:
+		t.Errorf("unexpected error: %v", err.Message)
+	default:
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidLogFormat = errors.New("log format must be one of: text, logfmt, json")
+	ErrInvalidLogLevel  = errors.New("log level must be one of: debug, info, warn, error")
+)
+
+// Logger for every event, replaced according to --log-format and --log-level
+// once flags have been parsed
+var logger = slog.New(newTextHandler(os.Stdout, slog.LevelInfo))
+
+// textHandler writes each record's message after a timestamp, matching the
+// human-readable output send has always produced. Attributes are only
+// included in the structured formats.
+type textHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+}
+
+func newTextHandler(w io.Writer, level slog.Leveler) *textHandler {
+	return &textHandler{
+		mu:    &sync.Mutex{},
+		w:     w,
+		level: level,
+	}
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := fmt.Fprintf(h.w, "%s | %s\n", r.Time.Format(logDate), r.Message)
+
+	return err
+}
+
+func (h *textHandler) WithAttrs(_ []slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *textHandler) WithGroup(_ string) slog.Handler {
+	return h
+}
+
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(s))
+	if err != nil {
+		return level, ErrInvalidLogLevel
+	}
+
+	return level, nil
+}
+
+func isValidLogLevel(s string) bool {
+	_, err := parseLogLevel(s)
+
+	return err == nil
+}
+
+func isValidLogFormat(s string) bool {
+	switch strings.ToLower(s) {
+	case "text", "logfmt", "json":
+		return true
+	default:
+		return false
+	}
+}
+
+// configureLogging replaces the logger according to LogFormat and LogLevel.
+func configureLogging() error {
+	level, err := parseLogLevel(LogLevel)
+	if err != nil {
+		return err
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+
+	switch strings.ToLower(LogFormat) {
+	case "text":
+		handler = newTextHandler(os.Stdout, level)
+	case "logfmt":
+		handler = slog.NewTextHandler(os.Stdout, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, options)
+	default:
+		return ErrInvalidLogFormat
+	}
+
+	logger = slog.New(handler)
+
+	return nil
+}
+
+// logEvent records an event under a stable name, with msg being the line
+// shown in text output and attrs the fields included in structured output.
+func logEvent(level slog.Level, event, msg string, attrs ...any) {
+	logger.Log(context.Background(), level, msg, append([]any{"event", event}, attrs...)...)
+}
@@ -0,0 +1,352 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	// Longest time allowed for delivering a message to the SMTP server
+	smtpTimeout = 30 * time.Second
+
+	// Templates for the mails sent, any of which can be redefined by the
+	// file given with --mail-template
+	defaultMailTemplate = `{{define "subject"}}
+{{- if eq (len .Files) 1}}{{(index .Files 0).Name}}{{else}}{{len .Files}} files{{end}} shared with you
+{{- end}}
+
+{{define "body" -}}
+{{if eq (len .Files) 1}}A file has{{else}}Some files have{{end}} been shared with you.
+{{range .Files}}
+{{.Name}} ({{bytes .Size}})
+{{with .SHA256}}SHA-256: {{.}}
+{{end}}{{range .URLs}}{{.}}
+{{end}}{{end}}
+{{- if .Remaining}}
+{{if eq (deref .Remaining) 1}}The links can be used once{{else}}The links can be used {{deref .Remaining}} times{{end}} in total.
+{{- end}}
+{{- if not .Expire.IsZero}}
+The links expire at {{date .Expire}}.
+{{- end}}
+{{end}}
+
+{{define "download_subject"}}{{base .Path}} has been downloaded{{end}}
+
+{{define "download_body" -}}
+{{.Path}} was downloaded by {{.Client}} at {{date .Time}} ({{bytes .Bytes}} in {{printf "%.1f" .Duration}}s).
+{{- if .Remaining}}
+
+{{if eq (deref .Remaining) 0}}No downloads remain.{{else}}{{deref .Remaining}} download(s) remain.{{end}}
+{{- end}}
+{{end}}`
+)
+
+var (
+	ErrInvalidMailAddress = errors.New("mail address is invalid")
+	ErrInvalidSMTPServer  = errors.New("SMTP server must be given as host:port")
+	ErrInvalidSMTPTLS     = errors.New("SMTP TLS mode must be one of: starttls, tls, none")
+	ErrNoMailFrom         = errors.New("sending mail requires --mail-from")
+	ErrNoStartTLS         = errors.New("SMTP server does not support STARTTLS (use --smtp-tls none to send without encryption)")
+)
+
+// Mailer sends share links, and download notifications, over SMTP.
+type Mailer struct {
+	server   string
+	username string
+	password string
+	tls      string
+	from     string
+	to       []string
+	notify   bool
+	template *template.Template
+	running  sync.WaitGroup
+
+	errorChannel chan<- Error
+}
+
+func isValidSMTPTLS(s string) bool {
+	switch s {
+	case "starttls", "tls", "none":
+		return true
+	default:
+		return false
+	}
+}
+
+func isValidSMTPServer(s string) bool {
+	_, _, err := net.SplitHostPort(s)
+
+	return err == nil
+}
+
+func isValidMailAddresses(addresses []string) bool {
+	for _, address := range addresses {
+		_, err := mail.ParseAddress(address)
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newMailer returns nil unless mail has been requested.
+func newMailer(errorChannel chan<- Error) (*Mailer, error) {
+	if len(MailTo) == 0 && !MailOnDownload {
+		return nil, nil
+	}
+
+	t, err := template.New("mail").Funcs(template.FuncMap{
+		"base":  func(path string) string { return path[strings.LastIndexAny(path, `/\`)+1:] },
+		"bytes": func(n int64) string { return formatBytes(float64(n)) },
+		"date":  func(t time.Time) string { return t.Format(time.RFC1123) },
+		"deref": func(n *int) int { return *n },
+	}).Parse(defaultMailTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	if MailTemplate != "" {
+		t, err = t.ParseFiles(MailTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Mailer{
+		server:       SMTPServer,
+		username:     SMTPUsername,
+		password:     SMTPPassword,
+		tls:          SMTPTLS,
+		from:         MailFrom,
+		to:           MailTo,
+		notify:       MailOnDownload,
+		template:     t,
+		errorChannel: errorChannel,
+	}, nil
+}
+
+// message renders the named subject and body templates into a complete
+// mail.
+func (m *Mailer) message(to []string, subject, body string, data any) ([]byte, error) {
+	var subjectText, bodyText bytes.Buffer
+
+	err := m.template.ExecuteTemplate(&subjectText, subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.template.ExecuteTemplate(&bodyText, body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subjectText.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", randomHex(16), hostname)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&msg)
+
+	_, err = w.Write(bodyText.Bytes())
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return msg.Bytes(), nil
+}
+
+// send delivers a message through the SMTP server.
+func (m *Mailer) send(to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(m.server)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig := &tls.Config{ServerName: host}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+
+	if m.tls == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", m.server, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", m.server)
+	}
+	if err != nil {
+		return err
+	}
+
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+
+		return err
+	}
+	defer c.Close()
+
+	if m.tls == "starttls" {
+		ok, _ := c.Extension("STARTTLS")
+		if !ok {
+			return ErrNoStartTLS
+		}
+
+		err = c.StartTLS(tlsConfig)
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.username != "" {
+		err = c.Auth(smtp.PlainAuth("", m.username, m.password, host))
+		if err != nil {
+			return err
+		}
+	}
+
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+
+	err = c.Mail(from.Address)
+	if err != nil {
+		return err
+	}
+
+	for _, recipient := range to {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return err
+		}
+
+		err = c.Rcpt(address.Address)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(msg)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// mailShare sends the links to a share to every --mail-to recipient.
+func (m *Mailer) mailShare(status *ShareStatus) error {
+	if m == nil || len(m.to) == 0 {
+		return nil
+	}
+
+	msg, err := m.message(m.to, "subject", "body", status)
+	if err != nil {
+		return err
+	}
+
+	err = m.send(m.to, msg)
+	if err != nil {
+		return fmt.Errorf("mailing links to %s: %w", strings.Join(m.to, ", "), err)
+	}
+
+	logEvent(slog.LevelInfo, "mail_sent", fmt.Sprintf("Mailed links to %s", strings.Join(m.to, ", ")),
+		"slug", status.Slug,
+		"to", strings.Join(m.to, ", "))
+
+	return nil
+}
+
+// queueShare mails the links to a share in the background.
+func (m *Mailer) queueShare(status ShareStatus) {
+	if m == nil || len(m.to) == 0 {
+		return
+	}
+
+	m.background(func() error {
+		return m.mailShare(&status)
+	})
+}
+
+// background runs fn without blocking the caller, reporting any error.
+func (m *Mailer) background(fn func() error) {
+	m.running.Add(1)
+
+	go func() {
+		defer m.running.Done()
+
+		err := fn()
+		if err != nil {
+			m.errorChannel <- Error{Message: fmt.Errorf("mail: %w", err)}
+		}
+	}()
+}
+
+// notifyDownload tells the sender about a completed download, if asked to.
+func (m *Mailer) notifyDownload(e *Event) {
+	if m == nil || !m.notify || e.Type != "download_completed" {
+		return
+	}
+
+	m.background(func() error {
+		to := []string{m.from}
+
+		msg, err := m.message(to, "download_subject", "download_body", e)
+		if err != nil {
+			return err
+		}
+
+		return m.send(to, msg)
+	})
+}
+
+// Wait blocks until every mail being sent has been delivered or failed.
+func (m *Mailer) Wait() {
+	if m == nil {
+		return
+	}
+
+	m.running.Wait()
+}
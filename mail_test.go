@@ -0,0 +1,275 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Delivery is a message received by the test SMTP server.
+type Delivery struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string
+}
+
+// startSMTP runs an SMTP server accepting every message, which it decodes
+// and sends to the returned channel.
+func startSMTP(t *testing.T) (string, <-chan Delivery) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { l.Close() })
+
+	deliveries := make(chan Delivery, 16)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go serveSMTP(t, conn, deliveries)
+		}
+	}()
+
+	return l.Addr().String(), deliveries
+}
+
+func serveSMTP(t *testing.T, conn net.Conn, deliveries chan<- Delivery) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+
+	reply := func(s string) {
+		io.WriteString(conn, s+"\r\n")
+	}
+
+	reply("220 localhost ESMTP")
+
+	var delivery Delivery
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			delivery = Delivery{From: strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")}
+
+			reply("250 OK")
+		case "RCPT":
+			delivery.To = append(delivery.To, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+
+			var data strings.Builder
+
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+
+				if line == ".\r\n" {
+					break
+				}
+
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(data.String()))
+			if err != nil {
+				t.Errorf("invalid message: %v", err)
+
+				return
+			}
+
+			body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+
+			delivery.Subject, _ = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			delivery.Body = strings.ReplaceAll(string(body), "\r\n", "\n")
+
+			deliveries <- delivery
+
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func receive(t *testing.T, deliveries <-chan Delivery) Delivery {
+	t.Helper()
+
+	select {
+	case delivery := <-deliveries:
+		return delivery
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+
+		return Delivery{}
+	}
+}
+
+func newTestMailer(t *testing.T, to []string, notify bool) (*Mailer, <-chan Delivery) {
+	t.Helper()
+
+	server, deliveries := startSMTP(t)
+
+	setFlag(t, &SMTPServer, server)
+	setFlag(t, &SMTPTLS, "none")
+	setFlag(t, &MailFrom, "Sender <sender@example.com>")
+	setFlag(t, &MailTo, to)
+	setFlag(t, &MailOnDownload, notify)
+
+	m, err := newMailer(make(chan Error, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m, deliveries
+}
+
+func TestMailShare(t *testing.T) {
+	m, deliveries := newTestMailer(t, []string{"a@example.com", "Bob <b@example.com>"}, false)
+
+	remaining := 1
+
+	err := m.mailShare(&ShareStatus{
+		Slug:      "/abc",
+		Remaining: &remaining,
+		Expire:    time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC),
+		Files: []FileStatus{{
+			Name:   "report.pdf",
+			Size:   2048,
+			SHA256: "e3b0c442",
+			URLs:   []string{"http://192.0.2.1:8080/abc/report.pdf"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+
+	if delivery.From != "sender@example.com" || strings.Join(delivery.To, ",") != "a@example.com,b@example.com" {
+		t.Errorf("envelope = %s -> %v", delivery.From, delivery.To)
+	}
+
+	if delivery.Subject != "report.pdf shared with you" {
+		t.Errorf("subject = %q", delivery.Subject)
+	}
+
+	for _, want := range []string{
+		"A file has been shared with you.",
+		"report.pdf (2.0 KiB)",
+		"SHA-256: e3b0c442",
+		"http://192.0.2.1:8080/abc/report.pdf",
+		"The links can be used once in total.",
+		"The links expire at Tue, 20 Oct 2026 18:00:00 UTC.",
+	} {
+		if !strings.Contains(delivery.Body, want) {
+			t.Errorf("body does not contain %q:\n%s", want, delivery.Body)
+		}
+	}
+}
+
+func TestNotifyDownload(t *testing.T) {
+	m, deliveries := newTestMailer(t, nil, true)
+
+	remaining := 2
+
+	m.notifyDownload(&Event{
+		Type:      "download_completed",
+		Time:      time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC),
+		Path:      "/srv/report.pdf",
+		Client:    "192.0.2.7",
+		Bytes:     2048,
+		Duration:  1.25,
+		Remaining: &remaining,
+	})
+
+	m.notifyDownload(&Event{Type: "download_started", Path: "/srv/report.pdf"})
+
+	m.Wait()
+
+	delivery := receive(t, deliveries)
+
+	if strings.Join(delivery.To, ",") != "sender@example.com" {
+		t.Errorf("recipients = %v, want the sender", delivery.To)
+	}
+
+	if delivery.Subject != "report.pdf has been downloaded" {
+		t.Errorf("subject = %q", delivery.Subject)
+	}
+
+	want := "/srv/report.pdf was downloaded by 192.0.2.7 at Tue, 20 Oct 2026 18:00:00 UTC (2.0 KiB in 1.2s).\n\n2 download(s) remain."
+	if !strings.Contains(delivery.Body, want) {
+		t.Errorf("body = %q, want %q", delivery.Body, want)
+	}
+
+	select {
+	case delivery := <-deliveries:
+		t.Errorf("unexpected mail for another event: %q", delivery.Subject)
+	default:
+	}
+}
+
+func TestMailTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.tmpl")
+
+	err := os.WriteFile(path, []byte(`{{define "subject"}}Files from {{.Slug}}{{end}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setFlag(t, &MailTemplate, path)
+
+	m, deliveries := newTestMailer(t, []string{"a@example.com"}, false)
+
+	err = m.mailShare(&ShareStatus{Slug: "/abc", Files: []FileStatus{{Name: "a"}, {Name: "b"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery := receive(t, deliveries)
+
+	if delivery.Subject != "Files from /abc" {
+		t.Errorf("subject = %q, want the one from the template", delivery.Subject)
+	}
+
+	if !strings.Contains(delivery.Body, "Some files have been shared with you.") {
+		t.Errorf("body does not use the default template:\n%s", delivery.Body)
+	}
+}
@@ -5,8 +5,12 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 package main
 
 import (
+	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -21,20 +25,138 @@ const (
 )
 
 var (
+	// Path to which a line is written for every request
+	AccessLogFile string
+
+	// Whether to compress rotated access logs
+	AccessLogCompress bool
+
+	// Format of the access log, either combined or json
+	AccessLogFormat string
+
+	// Number of rotated access logs to keep, or 0 to keep all of them
+	AccessLogKeep int
+
+	// Rotate the access log once it is this old, or this large
+	AccessLogMaxAge  time.Duration
+	AccessLogMaxSize string
+
+	// Address on which to serve the admin API, if any
+	Admin string
+
+	// Bearer token required by the admin API
+	AdminToken string
+
+	// Path to a hash-chained log of shares, downloads and shutdowns
+	AuditLogFile string
+
+	// Private key with which the audit log is signed
+	AuditKey string
+
+	// How often to sign the audit log, when it has a key
+	AuditSignInterval time.Duration
+
 	// The IP address on which send will listen
 	Bind string
 
+	// Delete files from disk once their last download has completed, and
+	// whether to do so without asking first
+	Burn    bool
+	BurnYes bool
+
+	// Path to a config file, instead of searching the default locations
+	ConfigFile string
+
 	// The number of times to serve selected file(s) before shutting down
 	Count int
 
+	// Run as a daemon, accepting shares through the control socket
+	Daemon bool
+
+	// How long to wait for active transfers to finish when shutting down
+	DrainTimeout time.Duration
+
 	// Exit on error, instead of just printing the error
 	ErrorExit bool
 
+	// Shell command whose output is served, run again for every download, and
+	// how many may run at once and for how long
+	Exec            string
+	ExecConcurrency int
+	ExecTimeout     time.Duration
+
+	// Duration or timestamp after which shares are no longer served
+	Expire string
+
+	// Maximum time a hook command may run before it is killed
+	HookTimeout time.Duration
+
+	// The length of time without a download starting after which send will shut down
+	Idle time.Duration
+
+	// Network interface whose addresses are used in URLs when bound to a wildcard address
+	Interface string
+
+	// How long to keep idle client connections open for reuse
+	KeepAliveTimeout time.Duration
+
+	// Addresses to listen on, instead of Bind and Port
+	Listen []string
+
 	// The length of randomly generated slugs and filenames
 	Length int
 
+	// Read files from disk on every request; refuse requests while a file is
+	// still being written, and follow files as they grow
+	LiveFile   bool
+	LiveFollow time.Duration
+	LiveSettle time.Duration
+
+	// Format and minimum level of log output
+	LogFormat string
+	LogLevel  string
+
+	// How client addresses are logged: full, truncate, hash or none
+	LogIP string
+
+	// Sender and recipients of mails with the links to shares
+	MailFrom string
+	MailTo   []string
+
+	// Mail the sender each time a file is downloaded
+	MailOnDownload bool
+
+	// Path to templates replacing those of the mails sent
+	MailTemplate string
+
+	// Path to a manifest describing additional shares
+	ManifestFile string
+
+	// Filename used in URLs for data from stdin or --exec, instead of a random one
+	Name string
+
+	// Serve files from a new process even if a daemon is running
+	NoDaemon bool
+
+	// Serve Prometheus metrics at /metrics
+	Metrics bool
+
+	// Duration or timestamp before which shares are not yet served
+	NotBefore string
+
+	// Commands run after each download, and once a share's downloads are used up
+	OnDownload  string
+	OnExhausted string
+
+	// OpenTelemetry collector to which download spans are exported over OTLP/HTTP
+	OTLPEndpoint string
+
 	// The port on which send will listen
-	Port int
+	Port string
+
+	// Maximum time to read request headers, and the full request
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
 
 	// Register http/pprof handlers
 	Profile bool
@@ -42,9 +164,41 @@ var (
 	// Randomize filenames in URLs
 	Randomize bool
 
+	// Maximum combined transfer rate across all clients
+	Rate string
+
+	// File from which rate limits are re-read on SIGUSR1
+	RateControl string
+
+	// Maximum transfer rate for each client
+	RatePerClient string
+
+	// Credentials, region and endpoint for objects shared from S3-compatible
+	// storage, which fall back to the standard AWS environment variables
+	S3AccessKey    string
+	S3SecretKey    string
+	S3SessionToken string
+	S3Region       string
+	S3Endpoint     string
+
 	// Scheme to use in generated URLs
 	Scheme string
 
+	// SMTP server through which mail is sent, and how to authenticate and encrypt the connection
+	SMTPServer   string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPTLS      string
+
+	// Directory in which shares are saved, so they can be restored after a restart
+	StateDir string
+
+	// How long a transfer may go without making progress before it is dropped
+	StallTimeout time.Duration
+
+	// Path to the daemon's control socket
+	Socket string
+
 	// The length of time after which send will shut down
 	Timeout time.Duration
 
@@ -57,53 +211,211 @@ var (
 
 	// Value to be used instead of http://<bind>:<port> in returned links
 	URL string
+
+	// Directories in which each file written is published as its own share,
+	// once it has been left unchanged for the settle time
+	Watch       []string
+	WatchSettle time.Duration
+
+	// URLs to which share events are posted, and the secret with which they are signed
+	WebhookURLs   []string
+	WebhookSecret string
+
+	// Initial deadline for writing a response, extended as long as the transfer makes progress
+	WriteTimeout time.Duration
 )
 
 func main() {
 	cmd := &cobra.Command{
 		Use:   "send [file]...",
 		Short: "Generates a one-off download link for one or more specified files.",
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			initializeConfig(cmd)
-		},
-		PreRunE: func(cmd *cobra.Command, args []string) error {
-			switch {
-			case TLSCert == "" && TLSKey != "" || TLSCert != "" && TLSKey == "":
-				return ErrInvalidTLSConfig
-			case Count < 0:
-				return ErrInvalidCount
-			case Length < 0:
-				return ErrInvalidLength
-			case Port < 1 || Port > 65535:
-				return ErrInvalidPort
-			case len(args) == 0 && !isFromPipe():
-				return ErrNoFile
-			}
-
-			if TLSCert != "" && TLSKey != "" && Scheme == "http" {
-				Scheme = "https"
+		Args:  cobra.ArbitraryArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			err := initializeConfig(cmd)
+			if err != nil {
+				return err
 			}
 
-			return nil
+			return configureLogging()
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateFlags(args)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if !NoDaemon {
+				client := handOff(cmd.Flags())
+				if client != nil {
+					return client.share(cmd, args)
+				}
+			}
+
 			return ServePage(args)
 		},
 	}
 
+	cmd.Flags().StringVar(&AccessLogFile, "access-log", "", "write a line for every request to this file")
+	cmd.Flags().BoolVar(&AccessLogCompress, "access-log-compress", false, "gzip rotated access logs")
+	cmd.Flags().StringVar(&AccessLogFormat, "access-log-format", "combined", "format of the access log (combined or json)")
+	cmd.Flags().IntVar(&AccessLogKeep, "access-log-keep", 0, "number of rotated access logs to keep (0 to keep all)")
+	cmd.Flags().DurationVar(&AccessLogMaxAge, "access-log-max-age", 0, "rotate the access log after this length of time")
+	cmd.Flags().StringVar(&AccessLogMaxSize, "access-log-max-size", "", "rotate the access log once it reaches this size (e.g. 100MiB)")
+	cmd.Flags().StringVar(&Admin, "admin", "", "serve the admin API on this address (e.g. tcp://127.0.0.1:8081, unix:///run/send-admin.sock), and keep running once all shares are finished")
+	cmd.Flags().StringVar(&AdminToken, "admin-token", "", "bearer token required by the admin API (optional on Unix sockets)")
+	cmd.Flags().StringVar(&AuditLogFile, "audit-log", "", "append a tamper-evident record of shares, downloads and shutdowns to this file")
+	cmd.Flags().StringVar(&AuditKey, "audit-key", "", "sign the audit log with this ed25519 private key (see send audit keygen)")
+	cmd.Flags().DurationVar(&AuditSignInterval, "audit-sign-interval", time.Minute, "sign new audit log records at this interval, as well as on shutdown")
 	cmd.Flags().StringVarP(&Bind, "bind", "b", "0.0.0.0", "address to bind to")
-	cmd.Flags().IntVarP(&Count, "count", "c", 0, "number of times to serve files")
+	cmd.Flags().BoolVar(&Burn, "burn", false, "securely delete files once the last download allowed by --count has completed")
+	cmd.PersistentFlags().StringVar(&ConfigFile, "config", "", "read settings from this config file (YAML, TOML or JSON) instead of searching the default locations")
+	cmd.Flags().IntVarP(&Count, "count", "c", 0, "number of times to serve files before they expire")
+	cmd.Flags().DurationVar(&DrainTimeout, "drain-timeout", time.Minute, "wait this long for active transfers to finish on shutdown (0 to wait indefinitely)")
+	cmd.Flags().StringVar(&Exec, "exec", "", "serve the output of this shell command, run again for every download")
+	cmd.Flags().IntVar(&ExecConcurrency, "exec-concurrency", 1, "maximum number of --exec commands running at once, refusing further downloads until one finishes (0 for no limit)")
+	cmd.Flags().DurationVar(&ExecTimeout, "exec-timeout", 0, "kill --exec commands which run for longer than this, failing the download (0 to disable)")
 	cmd.Flags().BoolVarP(&ErrorExit, "exit", "e", false, "shut down webserver on error, instead of just printing error")
+	cmd.Flags().StringVar(&Expire, "expire", "", "stop serving files after this duration or timestamp (e.g. 2h, 2026-10-20T18:00)")
+	cmd.Flags().DurationVar(&HookTimeout, "hook-timeout", time.Minute, "kill hook commands which run for longer than this (0 to disable)")
+	cmd.Flags().DurationVar(&Idle, "idle", 0, "shutdown after no downloads have been in progress for this length of time")
+	cmd.Flags().StringVarP(&Interface, "interface", "I", "", "only use addresses of this interface in returned URLs when bound to a wildcard address")
+	cmd.Flags().DurationVar(&KeepAliveTimeout, "keepalive-timeout", 10*time.Minute, "close idle client connections after this length of time")
 	cmd.Flags().IntVarP(&Length, "length", "l", 6, "length of url slug and obfuscated filenames")
-	cmd.Flags().IntVarP(&Port, "port", "p", 8080, "port to listen on")
+	cmd.Flags().StringArrayVar(&Listen, "listen", nil, "listen on this address instead of --bind and --port (e.g. tcp://[::]:8080, unix:///run/send.sock?mode=0660, fd://3), with +tls appended to the scheme for HTTPS (repeatable)")
+	cmd.Flags().BoolVar(&LiveFile, "live-file", false, "read files from disk on every request instead of once at startup, with an ETag based on their modification time and size")
+	cmd.Flags().DurationVar(&LiveFollow, "live-follow", 0, "keep sending data appended to live files, until they have not grown for this length of time")
+	cmd.Flags().DurationVar(&LiveSettle, "live-settle", 0, "refuse to serve live files modified within this length of time, as they may still be being written")
+	cmd.PersistentFlags().StringVar(&LogFormat, "log-format", "text", "format of log output (text, logfmt or json)")
+	cmd.Flags().StringVar(&LogIP, "log-ip", "full", "how client addresses are logged: full, truncate (to /24 or /48), hash (keyed, rotated daily) or none")
+	cmd.PersistentFlags().StringVar(&LogLevel, "log-level", "info", "minimum level of log output (debug, info, warn or error)")
+	cmd.Flags().StringVar(&MailFrom, "mail-from", "", "sender of mails, and recipient of download notifications")
+	cmd.Flags().BoolVar(&MailOnDownload, "mail-on-download", false, "mail the sender each time a file is downloaded")
+	cmd.Flags().StringVar(&MailTemplate, "mail-template", "", "read templates replacing those of the mails sent from this file")
+	cmd.Flags().StringArrayVar(&MailTo, "mail-to", nil, "mail the links to shares to this address (repeatable)")
+	cmd.Flags().StringVarP(&ManifestFile, "manifest", "f", "", "serve the shares described in this manifest file (YAML, TOML or JSON)")
+	cmd.Flags().BoolVar(&Metrics, "metrics", false, "serve Prometheus metrics at /metrics (always available on the admin API)")
+	cmd.Flags().StringVarP(&Name, "name", "n", "", "filename used in URLs for data from stdin or --exec, instead of a random one")
+	cmd.Flags().StringVar(&NotBefore, "not-before", "", "do not serve files until this duration or timestamp has passed")
+	cmd.Flags().StringVar(&OnDownload, "on-download", "", "run this shell command after each completed download, with details in SEND_* environment variables")
+	cmd.Flags().StringVar(&OnExhausted, "on-exhausted", "", "run this shell command once the last download allowed by a share's count has ended")
+	cmd.Flags().StringVar(&OTLPEndpoint, "otlp-endpoint", os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "export a trace span for each download to this OpenTelemetry collector (e.g. http://localhost:4318)")
+	cmd.Flags().StringVarP(&Port, "port", "p", "8080", "port to listen on, 0 or auto for any free port, or a range (e.g. 8080-8099) to use the first free one")
 	cmd.Flags().BoolVar(&Profile, "profile", false, "register net/http/pprof handlers")
 	cmd.Flags().BoolVarP(&Randomize, "randomize", "r", false, "randomize filenames")
-	cmd.Flags().StringVarP(&Scheme, "scheme", "s", "http", "scheme to use in returned URLs")
+	cmd.Flags().StringVar(&Rate, "rate", "", "limit combined transfer rate across all clients (e.g. 5MiB/s)")
+	cmd.Flags().StringVar(&RateControl, "rate-control", "", "re-read rate limits from this file on SIGUSR1")
+	cmd.Flags().StringVar(&RatePerClient, "rate-per-client", "", "limit transfer rate for each client (e.g. 1MiB/s)")
+	cmd.Flags().DurationVar(&ReadHeaderTimeout, "read-header-timeout", 10*time.Second, "maximum time to read request headers")
+	cmd.Flags().DurationVar(&ReadTimeout, "read-timeout", 10*time.Second, "maximum time to read an entire request")
+	cmd.Flags().StringVar(&S3AccessKey, "s3-access-key", "", "access key for s3:// paths (default from AWS_ACCESS_KEY_ID, anonymous if unset)")
+	cmd.Flags().StringVar(&S3Endpoint, "s3-endpoint", "", "endpoint of S3-compatible storage for s3:// paths, using path-style URLs (e.g. http://localhost:9000 for MinIO; AWS if unset)")
+	cmd.Flags().StringVar(&S3Region, "s3-region", "", "region for s3:// paths (default from AWS_REGION, or us-east-1)")
+	cmd.Flags().StringVar(&S3SecretKey, "s3-secret-key", "", "secret key for s3:// paths (default from AWS_SECRET_ACCESS_KEY)")
+	cmd.Flags().StringVar(&S3SessionToken, "s3-session-token", "", "session token for s3:// paths, when using temporary credentials (default from AWS_SESSION_TOKEN)")
+	cmd.Flags().StringVarP(&Scheme, "scheme", "s", "http", "scheme to use in returned URLs for listeners without TLS")
+	cmd.Flags().StringVar(&SMTPServer, "smtp-server", "localhost:587", "SMTP server through which mail is sent")
+	cmd.Flags().StringVar(&SMTPUsername, "smtp-username", "", "username with which to authenticate to the SMTP server")
+	cmd.Flags().StringVar(&SMTPPassword, "smtp-password", "", "password with which to authenticate to the SMTP server")
+	cmd.Flags().StringVar(&SMTPTLS, "smtp-tls", "starttls", "how to encrypt the connection to the SMTP server (starttls, tls or none)")
+	cmd.Flags().StringVar(&StateDir, "state-dir", "", "save shares to this directory, and restore them on startup")
+	cmd.Flags().DurationVar(&StallTimeout, "stall-timeout", time.Minute, "drop transfers which make no progress for this length of time (0 to disable)")
 	cmd.Flags().DurationVarP(&Timeout, "timeout", "t", 0, "shutdown after this length of time")
-	cmd.Flags().DurationVarP(&TimeoutInterval, "interval", "i", time.Minute, "display remaining time in timeout at this interval")
+	cmd.Flags().DurationVarP(&TimeoutInterval, "interval", "i", time.Minute, "display remaining time in timeouts at this interval")
 	cmd.Flags().StringVar(&TLSCert, "tls-cert", "", "path to TLS certificate")
 	cmd.Flags().StringVar(&TLSKey, "tls-key", "", "path to TLS keyfile")
-	cmd.Flags().StringVarP(&URL, "url", "u", "", "use this value instead of <scheme>://<bind>:<port> in returned URLs")
+	cmd.Flags().StringVarP(&URL, "url", "u", "", "use this value instead of <scheme>://<address>:<port> in returned URLs")
+	cmd.Flags().StringArrayVar(&Watch, "watch", nil, "publish each file written to this directory as its own share, and revoke it when the file is removed (repeatable)")
+	cmd.Flags().DurationVar(&WatchSettle, "watch-settle", 2*time.Second, "consider a file in a watched directory complete once unchanged for this length of time")
+	cmd.Flags().StringArrayVar(&WebhookURLs, "webhook", nil, "post share and download events as JSON to this URL (repeatable)")
+	cmd.Flags().StringVar(&WebhookSecret, "webhook-secret", "", "sign webhook requests with an HMAC-SHA256 of the body using this secret")
+	cmd.Flags().DurationVar(&WriteTimeout, "write-timeout", 5*time.Minute, "initial deadline for writing a response, extended while the transfer makes progress")
+	cmd.Flags().BoolVarP(&BurnYes, "yes", "y", false, "do not ask for confirmation before files are deleted by --burn")
+
+	daemon := &cobra.Command{
+		Use:   "daemon [file]...",
+		Short: "Runs a persistent server, to which other invocations of send add their files.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			Daemon = true
+
+			return validateFlags(args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return ServePage(args)
+		},
+	}
+
+	daemon.Flags().AddFlagSet(cmd.Flags())
+
+	ls := &cobra.Command{
+		Use:   "ls",
+		Short: "Lists the shares served by the daemon.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := dialDaemon(socketPath())
+			if err != nil {
+				return err
+			}
+
+			return client.list()
+		},
+	}
+
+	rm := &cobra.Command{
+		Use:   "rm <slug>...",
+		Short: "Revokes shares served by the daemon.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := dialDaemon(socketPath())
+			if err != nil {
+				return err
+			}
+
+			return client.remove(args)
+		},
+	}
+
+	cmd.Flags().BoolVar(&NoDaemon, "no-daemon", false, "serve files from this process even if a daemon is running")
+	cmd.PersistentFlags().StringVar(&Socket, "socket", "", "path to the daemon's control socket (default $XDG_RUNTIME_DIR/send.sock)")
+
+	audit := &cobra.Command{
+		Use:   "audit",
+		Short: "Manages audit logs and their signing keys.",
+	}
+
+	keygen := &cobra.Command{
+		Use:   "keygen <path>",
+		Short: "Generates a key pair for signing audit logs.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateAuditKey(args[0])
+		},
+	}
+
+	var publicKey string
+
+	verify := &cobra.Command{
+		Use:   "verify <path>",
+		Short: "Checks an audit log for edits, deletions and truncation.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var key ed25519.PublicKey
+
+			if publicKey != "" {
+				var err error
+
+				key, err = readPublicKey(publicKey)
+				if err != nil {
+					return err
+				}
+			}
+
+			return verifyAuditLog(args[0], key)
+		},
+	}
+
+	verify.Flags().StringVar(&publicKey, "public-key", "", "check signatures against this public key, instead of those embedded in the log")
+
+	audit.AddCommand(keygen, verify)
+
+	cmd.AddCommand(audit, daemon, ls, rm)
 
 	cmd.CompletionOptions.HiddenDefaultCmd = true
 
@@ -129,7 +441,93 @@ func main() {
 	}
 }
 
-func initializeConfig(cmd *cobra.Command) {
+func validateFlags(args []string) error {
+	switch {
+	case TLSCert == "" && TLSKey != "" || TLSCert != "" && TLSKey == "":
+		return ErrInvalidTLSConfig
+	case TLSCert == "" && listenersNeedTLS(Listen):
+		return ErrInvalidTLSConfig
+	case !isValidAccessLogFormat(AccessLogFormat):
+		return ErrInvalidAccessLogFormat
+	case !isValidSize(AccessLogMaxSize):
+		return ErrInvalidSize
+	case AccessLogKeep < 0 || AccessLogMaxAge < 0:
+		return ErrInvalidRotation
+	case AuditKey != "" && AuditLogFile == "":
+		return ErrNoAuditLog
+	case AuditSignInterval < 0:
+		return ErrInvalidSignInterval
+	case (len(MailTo) > 0 || MailOnDownload) && MailFrom == "":
+		return ErrNoMailFrom
+	case !isValidMailAddresses(MailTo) || MailFrom != "" && !isValidMailAddresses([]string{MailFrom}):
+		return ErrInvalidMailAddress
+	case !isValidSMTPServer(SMTPServer):
+		return ErrInvalidSMTPServer
+	case !isValidSMTPTLS(SMTPTLS):
+		return ErrInvalidSMTPTLS
+	case !isValidWebhooks(WebhookURLs):
+		return ErrInvalidWebhook
+	case !isValidLogIP(LogIP):
+		return ErrInvalidLogIP
+	case !isValidLogFormat(LogFormat):
+		return ErrInvalidLogFormat
+	case !isValidLogLevel(LogLevel):
+		return ErrInvalidLogLevel
+	case Count < 0:
+		return ErrInvalidCount
+	case Burn && Count == 0 && (ManifestFile == "" || len(args) > 0 || isFromPipe() || Exec != "" || len(Watch) > 0):
+		return ErrBurnWithoutCount
+	case DrainTimeout < 0:
+		return ErrInvalidDrainTimeout
+	case KeepAliveTimeout < 0 || ReadHeaderTimeout < 0 || ReadTimeout < 0 || StallTimeout < 0 || WriteTimeout < 0:
+		return ErrInvalidServerTimeout
+	case ExecConcurrency < 0 || ExecTimeout < 0:
+		return ErrInvalidExec
+	case !isValidS3Endpoint(S3Endpoint):
+		return ErrInvalidS3Endpoint
+	case strings.Contains(Name, "/"):
+		return ErrInvalidName
+	case LiveFollow < 0 || LiveSettle < 0:
+		return ErrInvalidLive
+	case StallTimeout > 0 && LiveFollow >= StallTimeout:
+		return ErrLiveFollowStall
+	case WatchSettle <= 0:
+		return ErrInvalidWatchSettle
+	case HookTimeout < 0:
+		return ErrInvalidHookTimeout
+	case Idle < 0:
+		return ErrInvalidIdle
+	case Length < 0:
+		return ErrInvalidLength
+	case !isValidPort(Port):
+		return ErrInvalidPort
+	case !isValidRate(Rate) || !isValidRate(RatePerClient):
+		return ErrInvalidRate
+	case !isValidTime(Expire) || !isValidTime(NotBefore):
+		return ErrInvalidExpiry
+	case Admin != "" && AdminToken == "" && !strings.HasPrefix(Admin, "unix://"):
+		return ErrNoAdminToken
+	case len(args) == 0 && !isFromPipe() && ManifestFile == "" && Admin == "" && !Daemon && len(Watch) == 0 && Exec == "":
+		return ErrNoFile
+	}
+
+	return nil
+}
+
+// configDirs lists the directories searched for a config file when none is
+// given explicitly, in order of preference.
+func configDirs() []string {
+	var dirs []string
+
+	dir, err := os.UserConfigDir()
+	if err == nil {
+		dirs = append(dirs, filepath.Join(dir, "send"))
+	}
+
+	return append(dirs, "/etc/send")
+}
+
+func initializeConfig(cmd *cobra.Command) error {
 	v := viper.New()
 
 	v.SetEnvPrefix("send")
@@ -138,16 +536,70 @@ func initializeConfig(cmd *cobra.Command) {
 
 	v.AutomaticEnv()
 
-	bindFlags(cmd, v)
+	configFile := ConfigFile
+	if configFile == "" {
+		configFile = v.GetString("config")
+	}
+
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+
+		err := v.ReadInConfig()
+		if err != nil {
+			return err
+		}
+	} else {
+		v.SetConfigName("config")
+
+		for _, dir := range configDirs() {
+			v.AddConfigPath(dir)
+		}
+
+		err := v.ReadInConfig()
+
+		var notFound viper.ConfigFileNotFoundError
+		if err != nil && !errors.As(err, &notFound) {
+			return err
+		}
+	}
+
+	return bindFlags(cmd, v)
 }
 
-func bindFlags(cmd *cobra.Command, v *viper.Viper) {
+func bindFlags(cmd *cobra.Command, v *viper.Viper) error {
+	var err error
+
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
-		configName := strings.ReplaceAll(f.Name, "-", "_")
+		if f.Changed || err != nil {
+			return
+		}
+
+		for _, configName := range []string{strings.ReplaceAll(f.Name, "-", "_"), f.Name} {
+			if !v.IsSet(configName) {
+				continue
+			}
 
-		if !f.Changed && v.IsSet(configName) {
 			val := v.Get(configName)
-			cmd.Flags().Set(f.Name, fmt.Sprintf("%v", val))
+
+			switch values := val.(type) {
+			case []any:
+				for _, value := range values {
+					err = cmd.Flags().Set(f.Name, fmt.Sprintf("%v", value))
+					if err != nil {
+						break
+					}
+				}
+			default:
+				err = cmd.Flags().Set(f.Name, fmt.Sprintf("%v", val))
+			}
+
+			if err != nil {
+				err = fmt.Errorf("invalid value for %s: %w", f.Name, err)
+			}
+
+			return
 		}
 	})
+
+	return err
 }
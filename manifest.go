@@ -0,0 +1,188 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrDuplicateSlug = errors.New("slug is used by more than one share")
+	ErrInvalidAllow  = errors.New("allowlist entries must be IP addresses or CIDR ranges")
+	ErrInvalidSlug   = errors.New("slug must not contain slashes")
+	ErrNoPaths       = errors.New("share has no paths")
+)
+
+// ShareConfig describes a single share in a manifest. Fields which are left
+// out fall back to the corresponding command-line flags.
+type ShareConfig struct {
+	Slug      string            `mapstructure:"slug" json:"slug,omitempty"`
+	Paths     []string          `mapstructure:"paths" json:"paths,omitempty"`
+	Count     *int              `mapstructure:"count" json:"count,omitempty"`
+	Expire    string            `mapstructure:"expire" json:"expire,omitempty"`
+	NotBefore string            `mapstructure:"not_before" json:"not_before,omitempty"`
+	Randomize *bool             `mapstructure:"randomize" json:"randomize,omitempty"`
+	Burn      *bool             `mapstructure:"burn" json:"burn,omitempty"`
+	Live      *bool             `mapstructure:"live" json:"live,omitempty"`
+	Password  string            `mapstructure:"password" json:"password,omitempty"`
+	Headers   map[string]string `mapstructure:"headers" json:"headers,omitempty"`
+	Allow     []string          `mapstructure:"allow" json:"allow,omitempty"`
+}
+
+type Manifest struct {
+	Shares []ShareConfig `mapstructure:"shares"`
+}
+
+// loadManifest reads a manifest in any format viper understands. Relative
+// paths are resolved against the directory containing the manifest, so that
+// it can be checked in alongside the files it shares.
+func loadManifest(path string) (*Manifest, error) {
+	v := viper.New()
+
+	v.SetConfigFile(path)
+
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+
+	err = v.UnmarshalExact(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+
+	for i := range manifest.Shares {
+		for j, p := range manifest.Shares[i].Paths {
+			if !filepath.IsAbs(p) && !isS3URL(p) {
+				manifest.Shares[i].Paths[j] = filepath.Join(dir, p)
+			}
+		}
+	}
+
+	return manifest, nil
+}
+
+func parseAllowlist(entries []string) ([]*net.IPNet, error) {
+	allow := make([]*net.IPNet, 0, len(entries))
+
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidAllow, entry)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			allow = append(allow, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAllow, entry)
+		}
+
+		allow = append(allow, ipNet)
+	}
+
+	return allow, nil
+}
+
+// newShare builds a share from its manifest entry, without registering any
+// of its files.
+func (c *ShareConfig) newShare(now time.Time) (*Share, error) {
+	if len(c.Paths) == 0 {
+		return nil, ErrNoPaths
+	}
+
+	share := &Share{
+		Slug:      "/" + generateRandomString(Length),
+		Count:     Count,
+		Randomize: Randomize,
+		Burn:      Burn,
+		Live:      LiveFile,
+		Password:  c.Password,
+		Headers:   c.Headers,
+	}
+
+	if c.Slug != "" {
+		slug := strings.Trim(c.Slug, "/")
+		if strings.Contains(slug, "/") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSlug, c.Slug)
+		}
+
+		share.Slug = "/" + slug
+	}
+
+	if c.Count != nil {
+		if *c.Count < 0 {
+			return nil, ErrInvalidCount
+		}
+
+		share.Count = *c.Count
+	}
+
+	if c.Randomize != nil {
+		share.Randomize = *c.Randomize
+	}
+
+	if c.Burn != nil {
+		share.Burn = *c.Burn
+	}
+
+	if c.Live != nil {
+		share.Live = *c.Live
+	}
+
+	if share.Burn && share.Count == 0 {
+		return nil, ErrBurnWithoutCount
+	}
+
+	expire := c.Expire
+	if expire == "" {
+		expire = Expire
+	}
+
+	notBefore := c.NotBefore
+	if notBefore == "" {
+		notBefore = NotBefore
+	}
+
+	var err error
+
+	share.Expire, err = parseTime(expire, now)
+	if err != nil {
+		return nil, err
+	}
+
+	share.NotBefore, err = parseTime(notBefore, now)
+	if err != nil {
+		return nil, err
+	}
+
+	share.Allow, err = parseAllowlist(c.Allow)
+	if err != nil {
+		return nil, err
+	}
+
+	return share, nil
+}
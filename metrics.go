@@ -0,0 +1,318 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Most distinct denied clients remembered, beyond which they are no
+	// longer counted
+	maxDeniedClients = 10000
+)
+
+// Upper bounds of the transfer duration histogram buckets, in seconds
+var durationBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+// Error types reported in metrics, checked in order
+var errorTypes = []struct {
+	err  error
+	name string
+}{
+	{ErrStalled, "stalled"},
+	{ErrNoFile, "no_file"},
+	{ErrDuplicateRoute, "duplicate_route"},
+	{ErrDuplicateSlug, "duplicate_slug"},
+	{os.ErrNotExist, "not_found"},
+	{os.ErrPermission, "permission"},
+}
+
+// Collector collects counters for the Prometheus endpoint. Gauges are read
+// from the registry and limits when scraped.
+type Collector struct {
+	mu sync.Mutex
+
+	requests map[int]uint64
+	errors   map[string]uint64
+	denied   map[string]uint64
+	clients  map[string]struct{}
+
+	bytes uint64
+
+	buckets  []uint64
+	duration float64
+	count    uint64
+
+	// When send will shut down due to --timeout
+	deadline time.Time
+
+	// Random key with which shares are identified, so that their slugs,
+	// which grant access to them, are not exposed
+	key []byte
+}
+
+func newCollector() *Collector {
+	return &Collector{
+		requests: make(map[int]uint64),
+		errors:   make(map[string]uint64),
+		denied:   make(map[string]uint64),
+		clients:  make(map[string]struct{}),
+		buckets:  make([]uint64, len(durationBuckets)),
+		key:      []byte(rand.Text()),
+	}
+}
+
+// shareID identifies a share in metrics without revealing its slug. IDs are
+// stable for the lifetime of the process.
+func (c *Collector) shareID(slug string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(slug))
+
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func (c *Collector) request(status int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests[status]++
+}
+
+// transfer records a finished download, counting its duration only if it
+// completed.
+func (c *Collector) transfer(t *Transfer, completed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bytes += uint64(t.Written())
+
+	if !completed {
+		return
+	}
+
+	seconds := time.Since(t.Started).Seconds()
+
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			c.buckets[i]++
+		}
+	}
+
+	c.duration += seconds
+	c.count++
+}
+
+func (c *Collector) error(err error) {
+	name := "other"
+
+	var netErr net.Error
+
+	for _, t := range errorTypes {
+		if errors.Is(err, t.err) {
+			name = t.name
+
+			break
+		}
+	}
+
+	if name == "other" && errors.As(err, &netErr) {
+		name = "network"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errors[name]++
+}
+
+// deny records a request refused by a share's allowlist or password.
+func (c *Collector) deny(reason, client string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.denied[reason]++
+
+	if reason == "not_allowed" && len(c.clients) < maxDeniedClients {
+		c.clients[client] = struct{}{}
+	}
+}
+
+func writeMetric(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys[K int | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// write renders all metrics in the Prometheus text exposition format.
+func (c *Collector) write(w io.Writer, registry *Registry, limits *Limits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	writeMetric(w, "send_http_requests_total", "counter", "HTTP requests served, by status code.")
+	for _, code := range sortedKeys(c.requests) {
+		fmt.Fprintf(w, "send_http_requests_total{code=\"%d\"} %d\n", code, c.requests[code])
+	}
+
+	writeMetric(w, "send_bytes_served_total", "counter", "Bytes of file content sent to clients.")
+	fmt.Fprintf(w, "send_bytes_served_total %d\n", c.bytes)
+
+	writeMetric(w, "send_active_transfers", "gauge", "Downloads currently in progress.")
+	fmt.Fprintf(w, "send_active_transfers %d\n", len(limits.transfers.list()))
+
+	writeMetric(w, "send_transfer_duration_seconds", "histogram", "Duration of completed downloads.")
+	for i, bound := range durationBuckets {
+		fmt.Fprintf(w, "send_transfer_duration_seconds_bucket{le=\"%s\"} %d\n", formatFloat(bound), c.buckets[i])
+	}
+	fmt.Fprintf(w, "send_transfer_duration_seconds_bucket{le=\"+Inf\"} %d\n", c.count)
+	fmt.Fprintf(w, "send_transfer_duration_seconds_sum %s\n", formatFloat(c.duration))
+	fmt.Fprintf(w, "send_transfer_duration_seconds_count %d\n", c.count)
+
+	shares := registry.list()
+
+	writeMetric(w, "send_share_downloads_total", "counter", "Downloads started from each share.")
+	for _, s := range shares {
+		_, served := s.counts()
+
+		fmt.Fprintf(w, "send_share_downloads_total{share=\"%s\"} %d\n", c.shareID(s.Slug), served)
+	}
+
+	writeMetric(w, "send_share_downloads_remaining", "gauge", "Downloads left for each share with a limited count.")
+	for _, s := range shares {
+		count, served := s.counts()
+		if count == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "send_share_downloads_remaining{share=\"%s\"} %d\n", c.shareID(s.Slug), max(count-served, 0))
+	}
+
+	writeMetric(w, "send_shutdown_seconds", "gauge", "Seconds until send shuts down, by reason.")
+	if Timeout != 0 {
+		fmt.Fprintf(w, "send_shutdown_seconds{reason=\"timeout\"} %s\n", formatFloat(max(time.Until(c.deadline).Seconds(), 0)))
+	}
+	if Idle != 0 {
+		fmt.Fprintf(w, "send_shutdown_seconds{reason=\"idle\"} %s\n", formatFloat(limits.idle.Remaining().Seconds()))
+	}
+
+	writeMetric(w, "send_errors_total", "counter", "Errors reported while serving, by type.")
+	for _, name := range sortedKeys(c.errors) {
+		fmt.Fprintf(w, "send_errors_total{type=\"%s\"} %d\n", name, c.errors[name])
+	}
+
+	writeMetric(w, "send_denied_requests_total", "counter", "Requests refused by a share's allowlist or password, by reason.")
+	for _, reason := range sortedKeys(c.denied) {
+		fmt.Fprintf(w, "send_denied_requests_total{reason=\"%s\"} %d\n", reason, c.denied[reason])
+	}
+
+	writeMetric(w, "send_denied_clients", "gauge", "Distinct client addresses refused by a share's allowlist, up to "+strconv.Itoa(maxDeniedClients)+".")
+	fmt.Fprintf(w, "send_denied_clients %d\n", len(c.clients))
+}
+
+// metricsHandler serves the metrics of the running server.
+func metricsHandler(registry *Registry, limits *Limits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		limits.metrics.write(w, registry, limits)
+	})
+}
+
+// statusWriter records the status code and size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+	bytes  int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recordRequests counts every response by its status code, and writes it to
+// the access log if there is one.
+func recordRequests(next http.Handler, limits *Limits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+
+		sw := &statusWriter{ResponseWriter: w}
+
+		// Requests are recorded even if the handler panics, as it does with
+		// http.ErrAbortHandler to abort a response, before passing the
+		// panic on to the server
+		defer func() {
+			p := recover()
+
+			switch {
+			case sw.status == 0 && p != nil:
+				sw.status = http.StatusInternalServerError
+			case sw.status == 0:
+				sw.status = http.StatusOK
+			}
+
+			limits.metrics.request(sw.status)
+
+			if limits.access != nil {
+				limits.access.write(accessRecord(r, sw.status, sw.bytes, started))
+			}
+
+			if p != nil {
+				panic(p)
+			}
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMetricsDoNotExposeSlugs(t *testing.T) {
+	registry := newRegistry(true)
+
+	share := &Share{Slug: "/SecretSlug", Count: 3}
+
+	addTestFile(t, registry, share, "/f.txt", "hello")
+
+	limits := newTestLimits()
+
+	w := httptest.NewRecorder()
+
+	metricsHandler(registry, limits).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := w.Body.String()
+
+	if strings.Contains(body, "SecretSlug") {
+		t.Errorf("metrics contain the slug:\n%s", body)
+	}
+
+	want := `send_share_downloads_remaining{share="` + limits.metrics.shareID(share.Slug) + `"} 3`
+	if !strings.Contains(body, want) {
+		t.Errorf("metrics do not contain %s:\n%s", want, body)
+	}
+
+	if newCollector().shareID(share.Slug) == limits.metrics.shareID(share.Slug) {
+		t.Error("share IDs do not depend on the key")
+	}
+}
+
+func TestRecordRequestsRecordsAbortedResponses(t *testing.T) {
+	limits := newTestLimits()
+
+	handler := recordRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+
+		panic(http.ErrAbortHandler)
+	}), limits)
+
+	func() {
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want %v", p, http.ErrAbortHandler)
+			}
+		}()
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if requests := limits.metrics.requests[http.StatusOK]; requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+}
+
+func TestDeniedClientsAreCapped(t *testing.T) {
+	c := newCollector()
+
+	for i := range maxDeniedClients + 10 {
+		c.deny("not_allowed", strconv.Itoa(i))
+	}
+
+	c.deny("not_allowed", "0")
+
+	if len(c.clients) != maxDeniedClients {
+		t.Errorf("remembered %d denied clients, want %d", len(c.clients), maxDeniedClients)
+	}
+
+	if c.denied["not_allowed"] != maxDeniedClients+11 {
+		t.Errorf("denied = %d, want every refusal counted", c.denied["not_allowed"])
+	}
+}
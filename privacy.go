@@ -0,0 +1,134 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidLogIP = errors.New("client address logging must be one of: full, truncate, hash, none")
+)
+
+// Key used to hash client addresses, replaced with a new random one each
+// day so that hashes cannot be linked across days, or reversed by trying
+// every address once the key is gone
+var ipHashKey struct {
+	mu  sync.Mutex
+	day string
+	key []byte
+}
+
+func isValidLogIP(s string) bool {
+	switch s {
+	case "full", "truncate", "hash", "none":
+		return true
+	default:
+		return false
+	}
+}
+
+func hashIP(ip string) string {
+	day := time.Now().UTC().Format(time.DateOnly)
+
+	ipHashKey.mu.Lock()
+
+	if ipHashKey.day != day {
+		ipHashKey.day = day
+		ipHashKey.key = make([]byte, 32)
+
+		rand.Read(ipHashKey.key)
+	}
+
+	mac := hmac.New(sha256.New, ipHashKey.key)
+
+	ipHashKey.mu.Unlock()
+
+	mac.Write([]byte(ip))
+
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// loggedIP returns a client address, with or without a port, in the form
+// selected by --log-ip. Every mode other than full drops the port.
+func loggedIP(addr string) string {
+	if LogIP == "full" || LogIP == "" {
+		return addr
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	switch LogIP {
+	case "truncate":
+		ip := net.ParseIP(host)
+
+		switch {
+		case ip == nil:
+			return host
+		case ip.To4() != nil:
+			return ip.Mask(net.CIDRMask(24, 32)).String()
+		default:
+			return ip.Mask(net.CIDRMask(48, 128)).String()
+		}
+	case "hash":
+		return hashIP(host)
+	default:
+		return "-"
+	}
+}
+
+// loggedError wraps an error, replacing the addresses of any network
+// operation it describes in its message with their form selected by --log-ip.
+type loggedError struct {
+	msg string
+	err error
+}
+
+func (e *loggedError) Error() string {
+	return e.msg
+}
+
+func (e *loggedError) Unwrap() error {
+	return e.err
+}
+
+// redactError returns err with the addresses of the network operation it
+// describes, such as the client of a failed write, in the form selected by
+// --log-ip.
+func redactError(err error) error {
+	if LogIP == "full" || LogIP == "" {
+		return err
+	}
+
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return err
+	}
+
+	var replacements []string
+
+	for _, addr := range []net.Addr{opErr.Source, opErr.Addr} {
+		if addr != nil {
+			replacements = append(replacements, addr.String(), loggedIP(addr.String()))
+		}
+	}
+
+	if len(replacements) == 0 {
+		return err
+	}
+
+	return &loggedError{msg: strings.NewReplacer(replacements...).Replace(err.Error()), err: err}
+}
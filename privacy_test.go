@@ -0,0 +1,101 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// setLogIP sets --log-ip for the rest of the test.
+func setLogIP(t *testing.T, mode string) {
+	t.Helper()
+
+	previous := LogIP
+
+	LogIP = mode
+
+	t.Cleanup(func() { LogIP = previous })
+}
+
+func TestLoggedIP(t *testing.T) {
+	tests := []struct {
+		mode string
+		in   string
+		want string
+	}{
+		{"full", "192.0.2.1:1234", "192.0.2.1:1234"},
+		{"full", "2001:db8::1", "2001:db8::1"},
+		{"truncate", "192.0.2.1:1234", "192.0.2.0"},
+		{"truncate", "192.0.2.1", "192.0.2.0"},
+		{"truncate", "[2001:db8:1:2::1]:1234", "2001:db8:1::"},
+		{"truncate", "2001:db8:1:2::1", "2001:db8:1::"},
+		{"truncate", "unix", "unix"},
+		{"none", "192.0.2.1:1234", "-"},
+		{"none", "2001:db8::1", "-"},
+	}
+
+	for _, tt := range tests {
+		setLogIP(t, tt.mode)
+
+		got := loggedIP(tt.in)
+		if got != tt.want {
+			t.Errorf("loggedIP(%q) with %s = %q, want %q", tt.in, tt.mode, got, tt.want)
+		}
+	}
+
+	setLogIP(t, "hash")
+
+	hashed := loggedIP("192.0.2.1:1234")
+
+	switch {
+	case len(hashed) != 16 || strings.Contains(hashed, "192.0.2"):
+		t.Errorf("loggedIP() with hash = %q, want a 16 character hash", hashed)
+	case loggedIP("192.0.2.1:5678") != hashed:
+		t.Error("loggedIP() with hash depends on the port")
+	case loggedIP("192.0.2.2:1234") == hashed:
+		t.Error("loggedIP() with hash is the same for different addresses")
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	opErr := &net.OpError{
+		Op:     "write",
+		Net:    "tcp",
+		Source: &net.TCPAddr{IP: net.ParseIP("198.51.100.7"), Port: 8080},
+		Addr:   &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234},
+		Err:    syscall.EPIPE,
+	}
+
+	err := fmt.Errorf("download failed: %w", opErr)
+
+	setLogIP(t, "full")
+
+	if redactError(err) != err {
+		t.Error("redactError() with full changed the error")
+	}
+
+	setLogIP(t, "truncate")
+
+	redacted := redactError(err)
+
+	want := "download failed: write tcp 198.51.100.0->192.0.2.0: broken pipe"
+	if redacted.Error() != want {
+		t.Errorf("redactError() = %q, want %q", redacted, want)
+	}
+
+	if !errors.Is(redacted, syscall.EPIPE) {
+		t.Error("redactError() does not wrap the original error")
+	}
+
+	plain := errors.New("192.0.2.1 is not an operation")
+	if redactError(plain) != plain {
+		t.Error("redactError() changed an error without a network operation")
+	}
+}
@@ -0,0 +1,357 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidRate = errors.New("rate must be a non-negative size per second (e.g. 5MiB/s)")
+)
+
+const (
+	// Largest chunk written to a client between rate limit checks
+	throttleChunk = 32 * 1024
+)
+
+var rateUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+}
+
+// Bucket is a token bucket shared by every writer drawing from it. Writers
+// reserve tokens up front and sleep off any resulting deficit, so concurrent
+// transfers are served in the order they asked rather than whoever wakes first.
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64) *Bucket {
+	return &Bucket{
+		rate:   rate,
+		tokens: rate,
+		last:   time.Now(),
+	}
+}
+
+func (b *Bucket) SetRate(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rate = rate
+	b.tokens = min(b.tokens, rate)
+	b.last = time.Now()
+}
+
+func (b *Bucket) reserve(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, max(b.rate, throttleChunk))
+	b.last = now
+
+	b.tokens -= float64(n)
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+type clientBucket struct {
+	bucket *Bucket
+	users  int
+}
+
+// Limiter hands out writers throttled by the global bucket and by a bucket
+// per client, the latter being shared by all of a client's open transfers.
+type Limiter struct {
+	global *Bucket
+
+	mu        sync.Mutex
+	perClient float64
+	clients   map[string]*clientBucket
+}
+
+func newLimiter(global, perClient float64) *Limiter {
+	return &Limiter{
+		global:    newBucket(global),
+		perClient: perClient,
+		clients:   make(map[string]*clientBucket),
+	}
+}
+
+func (l *Limiter) Set(global, perClient float64) {
+	l.global.SetRate(global)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.perClient = perClient
+
+	for _, c := range l.clients {
+		c.bucket.SetRate(perClient)
+	}
+}
+
+func (l *Limiter) Rates() (global, perClient float64) {
+	l.global.mu.Lock()
+	global = l.global.rate
+	l.global.mu.Unlock()
+
+	l.mu.Lock()
+	perClient = l.perClient
+	l.mu.Unlock()
+
+	return global, perClient
+}
+
+func (l *Limiter) acquire(client string) *Bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientBucket{bucket: newBucket(l.perClient)}
+
+		l.clients[client] = c
+	}
+
+	c.users++
+
+	return c.bucket
+}
+
+func (l *Limiter) release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.clients[client]
+	if !ok {
+		return
+	}
+
+	c.users--
+
+	if c.users <= 0 {
+		delete(l.clients, client)
+	}
+}
+
+// Writer wraps w so that writes are throttled for the given client. The
+// returned writer must be closed once the transfer is over.
+func (l *Limiter) Writer(ctx context.Context, w io.Writer, client string) *throttledWriter {
+	return &throttledWriter{
+		ctx:     ctx,
+		w:       w,
+		client:  client,
+		limiter: l,
+		buckets: []*Bucket{l.global, l.acquire(client)},
+	}
+}
+
+type throttledWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	client  string
+	limiter *Limiter
+	buckets []*Bucket
+	written int64
+}
+
+func (t *throttledWriter) Write(p []byte) (int, error) {
+	var written int
+
+	for len(p) > 0 {
+		chunk := min(len(p), throttleChunk)
+
+		for _, b := range t.buckets {
+			err := sleep(t.ctx, b.reserve(chunk))
+			if err != nil {
+				return written, err
+			}
+		}
+
+		n, err := t.w.Write(p[:chunk])
+		written += n
+		t.written += int64(n)
+		if err != nil {
+			return written, err
+		}
+
+		p = p[chunk:]
+	}
+
+	return written, nil
+}
+
+func (t *throttledWriter) Close() error {
+	t.limiter.release(t.client)
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func parseRate(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "/s")
+
+	if s == "" {
+		return 0, nil
+	}
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+
+	value, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || value < 0 {
+		return 0, ErrInvalidRate
+	}
+
+	unit, ok := rateUnits[strings.TrimSpace(s[i:])]
+	if !ok {
+		return 0, ErrInvalidRate
+	}
+
+	return value * unit, nil
+}
+
+func formatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", n, units[i])
+	}
+
+	return fmt.Sprintf("%.1f %s", n, units[i])
+}
+
+func formatRate(rate float64) string {
+	if rate <= 0 {
+		return "unlimited"
+	}
+
+	return formatBytes(rate) + "/s"
+}
+
+func formatThroughput(written int64, elapsed time.Duration) string {
+	seconds := max(elapsed.Seconds(), 0.001)
+
+	return fmt.Sprintf("%s in %s, %s",
+		formatBytes(float64(written)),
+		elapsed.Round(time.Millisecond),
+		formatRate(float64(written)/seconds))
+}
+
+// logRates records a change of rate limits, in bytes per second with 0
+// meaning unlimited.
+func logRates(global, perClient float64, prefix string) {
+	logEvent(slog.LevelInfo, "rate_limits_changed",
+		fmt.Sprintf("%sRate limits set to %s total, %s per client", prefix, formatRate(global), formatRate(perClient)),
+		"rate", global,
+		"rate_per_client", perClient)
+}
+
+// readRateControl parses a rate control file, made up of lines in the form
+// "rate=5MiB/s" or "rate-per-client=1MiB/s". Keys which are absent keep their
+// current values.
+func readRateControl(path string, global, perClient float64) (float64, float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return global, perClient, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return global, perClient, fmt.Errorf("%s: invalid line %q", path, line)
+		}
+
+		rate, err := parseRate(value)
+		if err != nil {
+			return global, perClient, fmt.Errorf("%s: %w", path, err)
+		}
+
+		switch strings.TrimSpace(key) {
+		case "rate":
+			global = rate
+		case "rate-per-client", "rate_per_client":
+			perClient = rate
+		default:
+			return global, perClient, fmt.Errorf("%s: unknown key %q", path, key)
+		}
+	}
+
+	return global, perClient, scanner.Err()
+}
+
+func isValidRate(s string) bool {
+	_, err := parseRate(s)
+
+	return err == nil
+}
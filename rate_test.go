@@ -0,0 +1,69 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  error
+	}{
+		{"", 0, nil},
+		{"0", 0, nil},
+		{"100", 100, nil},
+		{"100B/s", 100, nil},
+		{"1k", 1024, nil},
+		{"1KB/s", 1000, nil},
+		{"1KiB/s", 1024, nil},
+		{"5MiB/s", 5 << 20, nil},
+		{"1.5 MB/s", 1.5e6, nil},
+		{" 2GiB ", 2 << 30, nil},
+		{"1gb", 1e9, nil},
+		{"fast", 0, ErrInvalidRate},
+		{"5XB/s", 0, ErrInvalidRate},
+		{"1.2.3MiB", 0, ErrInvalidRate},
+		{"-5MiB", 0, ErrInvalidRate},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRate(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseRate(%q) error = %v, want %v", tt.in, err, tt.err)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("parseRate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 << 20, "5.0 MiB"},
+		{1 << 40, "1.0 TiB"},
+		{1 << 50, "1024.0 TiB"},
+	}
+
+	for _, tt := range tests {
+		got := formatBytes(tt.in)
+		if got != tt.want {
+			t.Errorf("formatBytes(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
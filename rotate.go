@@ -0,0 +1,238 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Suffix appended to rotated files, after the path of the live file
+	rotateLayout = "20060102T150405.000"
+)
+
+// RotatingFile is an append-only log file which is rotated once it grows
+// past MaxSize or has been open for longer than MaxAge, optionally
+// compressing rotated files and keeping only the newest Keep of them.
+type RotatingFile struct {
+	Path     string
+	MaxSize  int64
+	MaxAge   time.Duration
+	Compress bool
+	Keep     int
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+
+	// Rotated files are compressed and pruned one at a time in the
+	// background, and Close waits for this to finish
+	background sync.Mutex
+	pending    sync.WaitGroup
+}
+
+func openRotatingFile(path string) (*RotatingFile, error) {
+	r := &RotatingFile{Path: path}
+
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	r.f, r.size, r.opened = f, info.Size(), time.Now()
+
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize ||
+		r.MaxAge > 0 && time.Since(r.opened) >= r.MaxAge {
+		err := r.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.f.Write(p)
+
+	r.size += int64(n)
+
+	return n, err
+}
+
+// Reopen closes and reopens the file at Path, for when it has been moved
+// away by an external tool such as logrotate.
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.f.Close()
+
+	return r.open()
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.f.Close()
+
+	r.pending.Wait()
+
+	return err
+}
+
+// rotatedPath returns a path for a file rotated at now, which does not
+// replace one rotated earlier within the same millisecond.
+func (r *RotatingFile) rotatedPath(now time.Time) string {
+	for {
+		path := r.Path + "." + now.Format(rotateLayout)
+
+		_, err := os.Lstat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			_, err = os.Lstat(path + ".gz")
+		}
+		if err != nil {
+			return path
+		}
+
+		now = now.Add(time.Millisecond)
+	}
+}
+
+func (r *RotatingFile) rotate() error {
+	err := r.f.Close()
+	if err != nil {
+		return err
+	}
+
+	rotated := r.rotatedPath(time.Now())
+
+	err = os.Rename(r.Path, rotated)
+	if err != nil {
+		// Keep writing to the live file rather than failing every write
+		// from now on
+		return errors.Join(err, r.open())
+	}
+
+	err = r.open()
+	if err != nil {
+		return err
+	}
+
+	r.pending.Add(1)
+
+	go func() {
+		defer r.pending.Done()
+
+		r.background.Lock()
+		defer r.background.Unlock()
+
+		if r.Compress {
+			err := compressFile(rotated)
+			if err != nil {
+				logEvent(slog.LevelWarn, "compress_failed", fmt.Sprintf("Failed to compress %s: %s", rotated, err),
+					"path", rotated)
+			}
+		}
+
+		r.prune()
+	}()
+
+	return nil
+}
+
+// compressFile replaces the file at path with a gzipped copy.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+
+	w := gzip.NewWriter(out)
+
+	_, err = io.Copy(w, in)
+	if err == nil {
+		err = w.Close()
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		out.Close()
+		os.Remove(path + ".gz")
+
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// prune removes all but the newest Keep rotated files.
+func (r *RotatingFile) prune() {
+	if r.Keep <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(r.Path + ".*")
+	if err != nil {
+		return
+	}
+
+	var rotated []string
+
+	for _, match := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(match, r.Path+"."), ".gz")
+
+		_, err := time.Parse(rotateLayout, suffix)
+		if err == nil {
+			rotated = append(rotated, match)
+		}
+	}
+
+	sort.Strings(rotated)
+
+	for len(rotated) > r.Keep {
+		os.Remove(rotated[0])
+
+		rotated = rotated[1:]
+	}
+}
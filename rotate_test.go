@@ -0,0 +1,120 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	line := strings.Repeat("x", 9) + "\n"
+
+	tests := []struct {
+		name     string
+		maxSize  int64
+		maxAge   time.Duration
+		compress bool
+		keep     int
+		writes   int
+		live     int64
+		rotated  int
+		gzipped  int
+	}{
+		{"no limits", 0, 0, false, 0, 10, 100, 0, 0},
+		{"under size", 100, 0, false, 0, 10, 100, 0, 0},
+		{"over size", 30, 0, false, 0, 10, 10, 3, 0},
+		{"keep", 30, 0, false, 2, 10, 10, 2, 0},
+		{"compress", 30, 0, true, 0, 10, 10, 3, 3},
+		{"compress and keep", 30, 0, true, 1, 10, 10, 1, 1},
+		{"age", 0, time.Nanosecond, false, 0, 3, 10, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "access.log")
+
+			r, err := openRotatingFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r.MaxSize, r.MaxAge, r.Compress, r.Keep = tt.maxSize, tt.maxAge, tt.compress, tt.keep
+
+			for range tt.writes {
+				_, err = r.Write([]byte(line))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info.Size() != tt.live {
+				t.Errorf("live file size = %d, want %d", info.Size(), tt.live)
+			}
+
+			rotated, _ := filepath.Glob(path + ".*")
+			gzipped, _ := filepath.Glob(path + ".*.gz")
+
+			if len(rotated) != tt.rotated || len(gzipped) != tt.gzipped {
+				t.Errorf("rotated = %v, want %d files of which %d gzipped", rotated, tt.rotated, tt.gzipped)
+			}
+		})
+	}
+}
+
+func TestRotatingFileRenameFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+
+	r, err := openRotatingFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.MaxSize = 10
+
+	_, err = r.Write([]byte("123456789\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Removing the live file makes the rename fail
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Write([]byte("rotate\n"))
+	if err == nil {
+		t.Fatal("Write succeeded, want the failed rename reported")
+	}
+
+	_, err = r.Write([]byte("after\n"))
+	if err != nil {
+		t.Fatalf("Write after a failed rotation = %v, want the file reopened", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "after\n" {
+		t.Errorf("live file = %q, want %q", data, "after\n")
+	}
+}
@@ -0,0 +1,315 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Longest time allowed for checking that an object exists when it is
+	// shared
+	s3Timeout = 30 * time.Second
+
+	// SHA-256 of the empty payload sent with every request
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+var (
+	ErrInvalidS3Endpoint = errors.New("S3 endpoint must be an http or https URL")
+	ErrInvalidS3URL      = errors.New("S3 URLs must name an object, as s3://bucket/key")
+	ErrS3Request         = errors.New("S3 request failed")
+)
+
+// S3Object is an object in S3-compatible storage, fetched again on every
+// request.
+type S3Object struct {
+	Bucket string
+	Key    string
+
+	size int64
+}
+
+func isS3URL(path string) bool {
+	return strings.HasPrefix(path, "s3://")
+}
+
+func isValidS3Endpoint(endpoint string) bool {
+	if endpoint == "" {
+		return true
+	}
+
+	u, err := url.Parse(endpoint)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func parseS3URL(rawURL string) (*S3Object, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(rawURL, "s3://"), "/")
+	if !ok || bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidS3URL, rawURL)
+	}
+
+	return &S3Object{Bucket: bucket, Key: key}, nil
+}
+
+// s3Region returns the region in which requests are signed.
+func s3Region() string {
+	for _, region := range []string{S3Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")} {
+		if region != "" {
+			return region
+		}
+	}
+
+	return "us-east-1"
+}
+
+// s3Credentials returns the credentials with which requests are signed,
+// which are empty for anonymous access to public buckets.
+func s3Credentials() (accessKey, secretKey, sessionToken string) {
+	if S3AccessKey != "" {
+		return S3AccessKey, S3SecretKey, S3SessionToken
+	}
+
+	return os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN")
+}
+
+// s3Escape percent-encodes a path as required for signing, leaving only
+// unreserved characters and slashes.
+func s3Escape(path string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// url returns the location of the object: path-style on a custom endpoint,
+// which is what MinIO and most other implementations expect, and otherwise
+// virtual-hosted on AWS, unless the bucket name would not be valid in a
+// hostname covered by its certificate.
+func (o *S3Object) url() *url.URL {
+	u := &url.URL{Scheme: "https", Host: "s3." + s3Region() + ".amazonaws.com"}
+
+	path := "/" + o.Bucket + "/" + o.Key
+
+	switch {
+	case S3Endpoint != "":
+		endpoint, _ := url.Parse(S3Endpoint)
+
+		u.Scheme, u.Host = endpoint.Scheme, endpoint.Host
+	case !strings.Contains(o.Bucket, "."):
+		u.Host = o.Bucket + "." + u.Host
+
+		path = "/" + o.Key
+	}
+
+	u.Path, u.RawPath = path, s3Escape(path)
+
+	return u
+}
+
+// signS3 adds an AWS Signature Version 4 to a request without a body.
+func signS3(req *http.Request, region, accessKey, secretKey, sessionToken string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := amzDate[:8] + "/" + region + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3EmptyPayload)
+
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonical strings.Builder
+
+	fmt.Fprintf(&canonical, "%s\n%s\n%s\n", req.Method, req.URL.EscapedPath(), req.URL.RawQuery)
+
+	for _, name := range names {
+		fmt.Fprintf(&canonical, "%s:%s\n", name, headers[name])
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	fmt.Fprintf(&canonical, "\n%s\n%s", signedHeaders, s3EmptyPayload)
+
+	hash := sha256.Sum256([]byte(canonical.String()))
+
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{amzDate[:8], region, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, hex.EncodeToString(key)))
+}
+
+// request sends a signed request for the object, passing through the given
+// headers, and fails unless it succeeds.
+func (o *S3Object) request(ctx context.Context, method string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, o.url().String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	// Objects are passed through exactly as stored, rather than decompressed
+	req.Header.Set("Accept-Encoding", "identity")
+
+	accessKey, secretKey, sessionToken := s3Credentials()
+	if accessKey != "" {
+		signS3(req, s3Region(), accessKey, secretKey, sessionToken, time.Now())
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// An unsatisfiable range is passed back to the client, which asked for it
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable:
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	// S3 explains errors in an XML body, except in responses to HEAD
+	var s3Error struct {
+		Code    string
+		Message string
+	}
+
+	xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&s3Error)
+
+	if s3Error.Code != "" {
+		return nil, fmt.Errorf("%w: %s %s: %s: %s", ErrS3Request, method, o, s3Error.Code, s3Error.Message)
+	}
+
+	return nil, fmt.Errorf("%w: %s %s: %s", ErrS3Request, method, o, resp.Status)
+}
+
+func (o *S3Object) String() string {
+	return "s3://" + o.Bucket + "/" + o.Key
+}
+
+// loadObject checks that an object exists, and finds its size.
+func loadObject(rawURL string) (*S3Object, error) {
+	o, err := parseS3URL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+
+	resp, err := o.request(ctx, http.MethodHead, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body.Close()
+
+	o.size = resp.ContentLength
+
+	return o, nil
+}
+
+// registerObject publishes an object from S3-compatible storage, under the
+// same name it would have if it had been downloaded first.
+func registerObject(registry *Registry, rawURL string, share *Share, bases []string) (urls []string, path string, err error) {
+	o, err := loadObject(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file := &File{
+		Name:   fileName(share, o.Key),
+		Path:   o.String(),
+		object: o,
+	}
+
+	urls, err = publishFile(registry, share, file, bases)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return urls, file.Path, nil
+}
+
+// openObject fetches the object, passing through any range the client asked
+// for along with the headers describing the part returned.
+func openObject(r *http.Request, o *S3Object) (*Body, error) {
+	header := http.Header{}
+
+	for _, name := range []string{"Range", "If-Range"} {
+		if value := r.Header.Get(name); value != "" {
+			header.Set(name, value)
+		}
+	}
+
+	resp, err := o.request(r.Context(), http.MethodGet, header)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &Body{
+		ReadCloser: resp.Body,
+		Size:       resp.ContentLength,
+		Status:     resp.StatusCode,
+		Header:     http.Header{},
+	}
+
+	for _, name := range []string{"Accept-Ranges", "Content-Encoding", "Content-Range", "Content-Type", "ETag", "Last-Modified"} {
+		if value := resp.Header.Get(name); value != "" {
+			body.Header.Set(name, value)
+		}
+	}
+
+	return body, nil
+}
@@ -0,0 +1,613 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDuplicateRoute = errors.New("URL is already in use by another file")
+	ErrInvalidExpiry  = errors.New("expiry must be a duration (e.g. 2h) or a timestamp (e.g. 2026-10-20T18:00)")
+	ErrNoShare        = errors.New("no such share")
+)
+
+// Accepted layouts for absolute expiry and activation times, interpreted in
+// the local time zone unless an offset is given
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+type File struct {
+	// Filename component of the URL, including the leading slash
+	Name string
+
+	// Absolute path to the source, or a description of where it came from
+	Path string
+
+	content []byte
+
+	// Whether the content came from stdin or an upload rather than Path, and
+	// has to be spooled to the state directory to survive a restart
+	inline bool
+	spool  string
+
+	// Whether the content is read from Path on every request, rather than
+	// once when the file is published
+	live bool
+
+	// Shell command whose output is served, run again on every request
+	command string
+
+	// Object in S3-compatible storage, fetched again on every request
+	object *S3Object
+
+	sum     string
+	sumOnce sync.Once
+}
+
+func (f *File) Size() int64 {
+	if f.object != nil {
+		return f.object.size
+	}
+
+	if f.live {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return 0
+		}
+
+		return info.Size()
+	}
+
+	return int64(len(f.content))
+}
+
+// Checksum returns the hex SHA-256 of the file's content, computed the first
+// time it is needed. Live files, command output and objects in S3 have none,
+// since their content is not held in memory.
+func (f *File) Checksum() string {
+	if f.live || f.command != "" || f.object != nil {
+		return ""
+	}
+
+	f.sumOnce.Do(func() {
+		sum := sha256.Sum256(f.content)
+
+		f.sum = hex.EncodeToString(sum[:])
+	})
+
+	return f.sum
+}
+
+// Body is the content of a file as sent in response to a request.
+type Body struct {
+	io.ReadCloser
+
+	// Length of the content, or -1 if it is not known in advance
+	Size int64
+
+	// Status and headers of the response, if they depend on the source
+	Status int
+	Header http.Header
+}
+
+// open returns the content of the file in response to a request. flush
+// sends whatever has been written so far to the client.
+func (f *File) open(r *http.Request, flush func()) (*Body, error) {
+	switch {
+	case f.live:
+		return openLive(r.Context(), f, flush)
+	case f.command != "":
+		body, err := startCommand(r.Context(), f.command)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Body{ReadCloser: body, Size: -1}, nil
+	case f.object != nil:
+		return openObject(r, f.object)
+	}
+
+	return &Body{ReadCloser: io.NopCloser(bytes.NewReader(f.content)), Size: f.Size()}, nil
+}
+
+// Share is a set of files published under a single slug, which are served
+// until the share expires or has been downloaded Count times in total.
+//
+// Count, Expire and NotBefore may be changed while the share is being
+// served, so once it has been registered they must only be accessed through
+// its methods.
+type Share struct {
+	Slug      string
+	Files     []*File
+	Count     int
+	Expire    time.Time
+	NotBefore time.Time
+	Randomize bool
+
+	// Whether to delete the files from disk once Count downloads have
+	// completed
+	Burn bool
+
+	// Whether to read files from disk on every request
+	Live bool
+
+	// Optional password, checked against HTTP basic authentication
+	Password string
+
+	// Extra headers sent with every response
+	Headers map[string]string
+
+	// Networks allowed to download from the share, or nil for everyone
+	Allow []*net.IPNet
+
+	// Where the share was defined, so that a restart restores it instead of
+	// creating it again, or empty for shares added at runtime
+	origin string
+
+	mu       sync.Mutex
+	served   int
+	registry *Registry
+}
+
+// reserve claims one download from the share, returning the number of
+// downloads left afterwards, or -1 if the share has no limit. It fails once
+// the share is used up.
+func (s *Share) reserve() (remaining int, ok bool) {
+	s.mu.Lock()
+
+	if s.Count == 0 {
+		s.served++
+
+		s.mu.Unlock()
+
+		s.registry.save()
+
+		return -1, true
+	}
+
+	if s.served >= s.Count {
+		s.mu.Unlock()
+
+		return 0, false
+	}
+
+	s.served++
+
+	remaining = s.Count - s.served
+
+	s.mu.Unlock()
+
+	s.registry.save()
+
+	if remaining == 0 {
+		s.registry.check()
+	}
+
+	return remaining, true
+}
+
+func (s *Share) exhausted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Count != 0 && s.served >= s.Count
+}
+
+func (s *Share) expired(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return !s.Expire.IsZero() && !now.Before(s.Expire)
+}
+
+func (s *Share) pending(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return !s.NotBefore.IsZero() && now.Before(s.NotBefore)
+}
+
+func (s *Share) expiry() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Expire
+}
+
+// counts returns the share's download limit and how many downloads have
+// been made so far.
+func (s *Share) counts() (count, served int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.Count, s.served
+}
+
+// update changes the share's limits. Nil arguments are left unchanged.
+func (s *Share) update(count *int, expire *time.Time) {
+	s.mu.Lock()
+
+	if count != nil {
+		s.Count = *count
+	}
+
+	if expire != nil {
+		s.Expire = *expire
+	}
+
+	s.mu.Unlock()
+
+	if expire != nil && !expire.IsZero() {
+		time.AfterFunc(time.Until(*expire), s.registry.check)
+	}
+
+	s.registry.save()
+
+	s.registry.check()
+}
+
+// extend raises the share's count by n, counting from the downloads already
+// made if it was previously unlimited.
+func (s *Share) extend(n int) {
+	s.mu.Lock()
+
+	if s.Count == 0 {
+		s.Count = s.served
+	}
+
+	s.Count += n
+
+	s.mu.Unlock()
+
+	s.registry.save()
+}
+
+// allowed reports whether the given client address may download the share.
+func (s *Share) allowed(host string) bool {
+	if len(s.Allow) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range s.Allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authorized reports whether the request carries the share's password, with
+// any username being accepted.
+func (s *Share) authorized(r *http.Request) bool {
+	if s.Password == "" {
+		return true
+	}
+
+	_, password, ok := r.BasicAuth()
+
+	return ok && subtle.ConstantTimeCompare([]byte(password), []byte(s.Password)) == 1
+}
+
+func (s *Share) finished(now time.Time) bool {
+	return s.exhausted() || s.expired(now)
+}
+
+func (s *Share) status(now time.Time) string {
+	switch {
+	case s.expired(now):
+		return "expired"
+	case s.exhausted():
+		return "exhausted"
+	case s.pending(now):
+		return "pending"
+	default:
+		return "active"
+	}
+}
+
+type route struct {
+	share *Share
+	file  *File
+}
+
+// Registry tracks every share being served and routes requests to their
+// files. Unless it is persistent, it signals on done once all shares have
+// expired or been used up.
+type Registry struct {
+	mu         sync.RWMutex
+	shares     []*Share
+	routes     map[string]route
+	persistent bool
+	done       chan bool
+
+	// Where shares are saved after every change, if anywhere
+	state *StateStore
+
+	// Called with each share once it has been removed
+	removed []func(*Share)
+}
+
+func newRegistry(persistent bool) *Registry {
+	return &Registry{
+		routes:     make(map[string]route),
+		persistent: persistent,
+		done:       make(chan bool, 1),
+	}
+}
+
+func (r *Registry) add(s *Share) error {
+	r.mu.Lock()
+
+	for _, existing := range r.shares {
+		if existing.Slug == s.Slug {
+			r.mu.Unlock()
+
+			return fmt.Errorf("%w: %s", ErrDuplicateSlug, s.Slug)
+		}
+	}
+
+	s.registry = r
+
+	r.shares = append(r.shares, s)
+
+	r.mu.Unlock()
+
+	if expire := s.expiry(); !expire.IsZero() {
+		time.AfterFunc(time.Until(expire), r.check)
+	}
+
+	r.save()
+
+	return nil
+}
+
+// addFile publishes a file as part of a share which has already been added.
+func (r *Registry) addFile(s *Share, f *File) error {
+	r.mu.Lock()
+
+	path := s.Slug + f.Name
+
+	if _, exists := r.routes[path]; exists {
+		r.mu.Unlock()
+
+		return fmt.Errorf("%w: %s", ErrDuplicateRoute, path)
+	}
+
+	r.routes[path] = route{share: s, file: f}
+
+	s.Files = append(s.Files, f)
+
+	r.mu.Unlock()
+
+	r.save()
+
+	return nil
+}
+
+// remove unpublishes a share and all of its files.
+func (r *Registry) remove(s *Share) {
+	r.mu.Lock()
+
+	for _, f := range s.Files {
+		delete(r.routes, s.Slug+f.Name)
+	}
+
+	found := false
+
+	for i, existing := range r.shares {
+		if existing == s {
+			r.shares = append(r.shares[:i], r.shares[i+1:]...)
+
+			found = true
+
+			break
+		}
+	}
+
+	callbacks := r.removed
+
+	r.mu.Unlock()
+
+	r.save()
+
+	if found {
+		for _, fn := range callbacks {
+			fn(s)
+		}
+	}
+}
+
+// onRemove calls fn with every share removed from now on, however that
+// happens.
+func (r *Registry) onRemove(fn func(*Share)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removed = append(r.removed, fn)
+}
+
+// revoke removes the share with the given slug, which takes effect for any
+// subsequent requests immediately.
+func (r *Registry) revoke(slug string) (*Share, error) {
+	s := r.lookupShare(slug)
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoShare, slug)
+	}
+
+	r.remove(s)
+
+	r.check()
+
+	return s, nil
+}
+
+// files returns the files of a registered share.
+func (r *Registry) files(s *Share) []*File {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*File(nil), s.Files...)
+}
+
+// save writes the current shares to the state directory, if one is set.
+func (r *Registry) save() {
+	if r.state != nil {
+		r.state.save(r)
+	}
+}
+
+// lookupOrigin returns the share defined at the given origin, if any.
+func (r *Registry) lookupOrigin(origin string) *Share {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, s := range r.shares {
+		if s.origin == origin {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (r *Registry) lookup(path string) (*Share, *File) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	route, ok := r.routes[path]
+	if !ok {
+		return nil, nil
+	}
+
+	return route.share, route.file
+}
+
+func (r *Registry) lookupShare(slug string) *Share {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, s := range r.shares {
+		if s.Slug == slug {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (r *Registry) list() []*Share {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	shares := append([]*Share(nil), r.shares...)
+
+	sort.SliceStable(shares, func(i, j int) bool {
+		return shares[i].Slug < shares[j].Slug
+	})
+
+	return shares
+}
+
+func (r *Registry) finished() bool {
+	now := time.Now()
+
+	for _, s := range r.list() {
+		if !s.finished(now) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *Registry) check() {
+	if r.persistent || !r.finished() {
+		return
+	}
+
+	select {
+	case r.done <- true:
+	default:
+	}
+}
+
+// nextExpiry returns the soonest expiry among shares which are still live.
+func (r *Registry) nextExpiry() (*Share, time.Time) {
+	var next *Share
+	var nextExpire time.Time
+
+	now := time.Now()
+
+	for _, s := range r.list() {
+		expire := s.expiry()
+
+		if expire.IsZero() || s.finished(now) {
+			continue
+		}
+
+		if next == nil || expire.Before(nextExpire) {
+			next, nextExpire = s, expire
+		}
+	}
+
+	return next, nextExpire
+}
+
+// parseTime accepts either a duration, counted from now, or an absolute
+// timestamp in one of timeLayouts. An empty string yields the zero time.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err == nil {
+		if d < 0 {
+			return time.Time{}, ErrInvalidExpiry
+		}
+
+		return now.Add(d), nil
+	}
+
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, ErrInvalidExpiry
+}
+
+func isValidTime(s string) bool {
+	_, err := parseTime(s, time.Now())
+
+	return err == nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		in   string
+		want time.Time
+		err  error
+	}{
+		{"", time.Time{}, nil},
+		{"2h", now.Add(2 * time.Hour), nil},
+		{"90s", now.Add(90 * time.Second), nil},
+		{"0s", now, nil},
+		{"-1h", time.Time{}, ErrInvalidExpiry},
+		{"2026-10-20T18:00:00Z", time.Date(2026, 10, 20, 18, 0, 0, 0, time.UTC), nil},
+		{"2026-10-20T18:00:00+02:00", time.Date(2026, 10, 20, 16, 0, 0, 0, time.UTC), nil},
+		{"2026-10-20T18:00:30", time.Date(2026, 10, 20, 18, 0, 30, 0, time.Local), nil},
+		{"2026-10-20T18:00", time.Date(2026, 10, 20, 18, 0, 0, 0, time.Local), nil},
+		{"2026-10-20 18:00:30", time.Date(2026, 10, 20, 18, 0, 30, 0, time.Local), nil},
+		{"2026-10-20 18:00", time.Date(2026, 10, 20, 18, 0, 0, 0, time.Local), nil},
+		{"2026-10-20", time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local), nil},
+		{"tomorrow", time.Time{}, ErrInvalidExpiry},
+		{"2026-13-01", time.Time{}, ErrInvalidExpiry},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTime(tt.in, now)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseTime(%q) error = %v, want %v", tt.in, err, tt.err)
+
+			continue
+		}
+
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpiredShareIsNotReserved(t *testing.T) {
+	registry := newRegistry(true)
+
+	share := &Share{Slug: "/test", Count: 2, Expire: time.Now().Add(-time.Minute)}
+
+	addTestFile(t, registry, share, "/f.txt", "hello")
+
+	for range 3 {
+		resp := serveTest(t, registry, newTestLimits(), httptest.NewRequest(http.MethodGet, "/test/f.txt", nil))
+		if resp.Code != http.StatusGone {
+			t.Errorf("status = %d, want %d", resp.Code, http.StatusGone)
+		}
+	}
+
+	_, served := share.counts()
+	if served != 0 {
+		t.Errorf("served = %d, want 0", served)
+	}
+}
+
+func TestDisallowedClientIsRefused(t *testing.T) {
+	registry := newRegistry(true)
+
+	_, allow, _ := net.ParseCIDR("10.0.0.0/8")
+
+	share := &Share{Slug: "/test", Allow: []*net.IPNet{allow}}
+
+	addTestFile(t, registry, share, "/f.txt", "hello")
+
+	limits := newTestLimits()
+
+	r := httptest.NewRequest(http.MethodGet, "/test/f.txt", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	resp := serveTest(t, registry, limits, r)
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", resp.Code, http.StatusForbidden)
+	}
+
+	if denied := limits.metrics.denied["not_allowed"]; denied != 1 {
+		t.Errorf("denied = %d, want 1", denied)
+	}
+}
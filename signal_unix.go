@@ -0,0 +1,18 @@
+//go:build !windows
+
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals which cause the rate control file to be re-read
+var rateSignals = []os.Signal{syscall.SIGUSR1}
+
+// Signals which cause log files to be reopened, after being moved by logrotate
+var reopenSignals = []os.Signal{syscall.SIGHUP}
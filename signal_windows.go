@@ -0,0 +1,17 @@
+//go:build windows
+
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"os"
+)
+
+// Windows has no user-defined signals, so rate limits can only be set at startup
+var rateSignals = []os.Signal{}
+
+// Windows has no SIGHUP, so log files are only ever rotated by send itself
+var reopenSignals = []os.Signal{}
@@ -0,0 +1,309 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// Name of the file holding share metadata within the state directory
+	stateFile = "shares.json"
+
+	// Directory within the state directory holding spooled content
+	spoolDir = "spool"
+)
+
+// FileState is a file as saved to the state directory. Spool names the
+// spooled copy of content which did not come from Path.
+type FileState struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Spool   string `json:"spool,omitempty"`
+	Command string `json:"command,omitempty"`
+}
+
+// ShareState is a share as saved to the state directory.
+type ShareState struct {
+	Slug      string            `json:"slug"`
+	Origin    string            `json:"origin,omitempty"`
+	Count     int               `json:"count"`
+	Served    int               `json:"served"`
+	Expire    time.Time         `json:"expire,omitzero"`
+	NotBefore time.Time         `json:"not_before,omitzero"`
+	Randomize bool              `json:"randomize,omitempty"`
+	Burn      bool              `json:"burn,omitempty"`
+	Live      bool              `json:"live,omitempty"`
+	Password  string            `json:"password,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Allow     []string          `json:"allow,omitempty"`
+	Files     []FileState       `json:"files"`
+}
+
+// State is everything needed to resume serving after a restart. Deadline is
+// only carried over if the process is restarted with the same Timeout.
+type State struct {
+	Deadline time.Time     `json:"deadline,omitzero"`
+	Timeout  time.Duration `json:"timeout,omitempty"`
+	Shares   []ShareState  `json:"shares"`
+}
+
+// StateStore saves shares to a directory whenever they change.
+type StateStore struct {
+	dir          string
+	deadline     time.Time
+	errorChannel chan<- Error
+
+	mu sync.Mutex
+}
+
+// openStateStore creates the state directory if needed, and returns the
+// state saved by a previous run, if any.
+func openStateStore(dir string, errorChannel chan<- Error) (*StateStore, *State, error) {
+	err := os.MkdirAll(filepath.Join(dir, spoolDir), 0700)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store := &StateStore{
+		dir:          dir,
+		errorChannel: errorChannel,
+	}
+
+	state := &State{}
+
+	data, err := os.ReadFile(filepath.Join(dir, stateFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return store, state, nil
+	case err != nil:
+		return nil, nil, err
+	}
+
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return store, state, nil
+}
+
+// writeFile atomically replaces the file at path.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+
+	err := os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// spool saves inline content to the spool directory under its hash, unless
+// it has been saved already.
+func (st *StateStore) spool(f *File) error {
+	if f.spool != "" {
+		return nil
+	}
+
+	name := f.Checksum()
+
+	path := filepath.Join(st.dir, spoolDir, name)
+
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = writeFile(path, f.content)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.spool = name
+
+	return nil
+}
+
+func (st *StateStore) save(r *Registry) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	err := st.write(r)
+	if err != nil {
+		st.errorChannel <- Error{Message: err}
+	}
+}
+
+func (st *StateStore) write(r *Registry) error {
+	state := &State{
+		Deadline: st.deadline,
+		Timeout:  Timeout,
+	}
+
+	spooled := make(map[string]bool)
+
+	for _, s := range r.list() {
+		s.mu.Lock()
+
+		share := ShareState{
+			Slug:      s.Slug,
+			Origin:    s.origin,
+			Count:     s.Count,
+			Served:    s.served,
+			Expire:    s.Expire,
+			NotBefore: s.NotBefore,
+			Randomize: s.Randomize,
+			Burn:      s.Burn,
+			Live:      s.Live,
+			Password:  s.Password,
+			Headers:   s.Headers,
+		}
+
+		s.mu.Unlock()
+
+		for _, n := range s.Allow {
+			share.Allow = append(share.Allow, n.String())
+		}
+
+		for _, f := range r.files(s) {
+			file := FileState{
+				Name:    f.Name,
+				Path:    f.Path,
+				Command: f.command,
+			}
+
+			if f.inline {
+				err := st.spool(f)
+				if err != nil {
+					return err
+				}
+
+				file.Spool = f.spool
+
+				spooled[f.spool] = true
+			}
+
+			share.Files = append(share.Files, file)
+		}
+
+		state.Shares = append(state.Shares, share)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(filepath.Join(st.dir, stateFile), data)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(st.dir, spoolDir))
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !spooled[entry.Name()] {
+			os.Remove(filepath.Join(st.dir, spoolDir, entry.Name()))
+		}
+	}
+
+	return nil
+}
+
+// restore registers every share from a previous run which has not yet
+// expired or been used up, under the same URLs.
+func (st *StateStore) restore(state *State, registry *Registry, bases []string) (urls, paths []string) {
+	now := time.Now()
+
+	for _, saved := range state.Shares {
+		allow, err := parseAllowlist(saved.Allow)
+		if err != nil {
+			st.errorChannel <- Error{Message: err}
+
+			continue
+		}
+
+		share := &Share{
+			Slug:      saved.Slug,
+			Count:     saved.Count,
+			Expire:    saved.Expire,
+			NotBefore: saved.NotBefore,
+			Randomize: saved.Randomize,
+			Burn:      saved.Burn,
+			Live:      saved.Live,
+			Password:  saved.Password,
+			Headers:   saved.Headers,
+			Allow:     allow,
+			origin:    saved.Origin,
+			served:    saved.Served,
+		}
+
+		if share.finished(now) {
+			continue
+		}
+
+		err = registry.add(share)
+		if err != nil {
+			st.errorChannel <- Error{Message: err}
+
+			continue
+		}
+
+		for _, saved := range saved.Files {
+			file := &File{
+				Name:    saved.Name,
+				Path:    saved.Path,
+				inline:  saved.Spool != "",
+				spool:   saved.Spool,
+				command: saved.Command,
+			}
+
+			file.live = share.Live && !file.inline && file.command == "" && !isS3URL(file.Path)
+
+			source := file.Path
+			if file.inline {
+				source = filepath.Join(st.dir, spoolDir, file.spool)
+			}
+
+			switch {
+			case file.command != "":
+				// Generated on every request, so there is nothing to read
+			case isS3URL(file.Path):
+				file.object, err = loadObject(file.Path)
+			case file.live:
+				_, err = os.Stat(source)
+			default:
+				file.content, err = readFile(source)
+			}
+			if err == nil {
+				err = registry.addFile(share, file)
+			}
+			if err != nil {
+				st.errorChannel <- Error{Message: err}
+
+				continue
+			}
+
+			for _, url := range fileURLs(bases, share, file) {
+				urls = append(urls, url)
+				paths = append(paths, file.Path)
+			}
+		}
+
+		if len(share.Files) == 0 {
+			registry.remove(share)
+		}
+	}
+
+	return urls, paths
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// addInlineFile registers content read from stdin or the admin API, which
+// only survives a restart through the spool.
+func addInlineFile(t *testing.T, registry *Registry, share *Share, name, content string) *File {
+	t.Helper()
+
+	if share.registry == nil {
+		err := registry.add(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file := &File{Name: name, Path: name, inline: true, content: []byte(content)}
+
+	err := registry.addFile(share, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func openTestState(t *testing.T, dir string) (*StateStore, *State) {
+	t.Helper()
+
+	store, state, err := openStateStore(dir, make(chan Error, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, state
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(t.TempDir(), "on-disk.txt")
+
+	err := os.WriteFile(path, []byte("from disk"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, _ := openTestState(t, dir)
+
+	registry := newRegistry(true)
+	registry.state = store
+
+	piped := &Share{Slug: "/piped", Count: 3, served: 1}
+	addInlineFile(t, registry, piped, "/stdin.txt", "from stdin")
+
+	disk := &Share{Slug: "/disk"}
+
+	err = registry.add(disk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = registry.addFile(disk, &File{Name: "/on-disk.txt", Path: path, content: []byte("from disk")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, state := openTestState(t, dir)
+
+	restored := newRegistry(true)
+
+	urls, _ := store.restore(state, restored, []string{"http://host"})
+
+	slices.Sort(urls)
+
+	want := []string{"http://host/disk/on-disk.txt", "http://host/piped/stdin.txt"}
+	if !slices.Equal(urls, want) {
+		t.Fatalf("restored URLs = %v, want %v", urls, want)
+	}
+
+	share := restored.lookupShare("/piped")
+	if share.Count != 3 || share.served != 1 {
+		t.Errorf("restored count = %d, served = %d, want 3 and 1", share.Count, share.served)
+	}
+
+	for slug, content := range map[string]string{"/piped": "from stdin", "/disk": "from disk"} {
+		files := restored.files(restored.lookupShare(slug))
+		if len(files) != 1 || string(files[0].content) != content {
+			t.Errorf("restored %s content = %v, want %q", slug, files, content)
+		}
+	}
+}
+
+func TestBurnKeepsSharedSpool(t *testing.T) {
+	dir := t.TempDir()
+
+	store, _ := openTestState(t, dir)
+
+	registry := newRegistry(true)
+	registry.state = store
+
+	first := &Share{Slug: "/first", Burn: true}
+	file := addInlineFile(t, registry, first, "/a.txt", "same content")
+
+	second := &Share{Slug: "/second"}
+	addInlineFile(t, registry, second, "/b.txt", "same content")
+
+	if paths := burnPaths(first); len(paths) != 0 {
+		t.Errorf("burnPaths = %v, want the shared spool left alone", paths)
+	}
+
+	err := burn(first, newTestLimits())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry.remove(first)
+
+	store, state := openTestState(t, dir)
+
+	restored := newRegistry(true)
+
+	urls, _ := store.restore(state, restored, []string{"http://host"})
+	if !slices.Equal(urls, []string{"http://host/second/b.txt"}) {
+		t.Fatalf("restored URLs = %v, want only the second share", urls)
+	}
+
+	registry.remove(second)
+
+	if paths := burnPaths(first); len(paths) != 1 {
+		t.Errorf("burnPaths = %v, want the spool once no other share uses it", paths)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, spoolDir, file.spool))
+	if !os.IsNotExist(err) {
+		t.Errorf("spool still exists after its last share was removed: %v", err)
+	}
+}
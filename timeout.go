@@ -0,0 +1,62 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidServerTimeout = errors.New("server timeouts must be non-negative durations")
+	ErrStalled              = errors.New("transfer stalled")
+)
+
+// stallWriter pushes the connection's write deadline back before every
+// write, so that a transfer may run for as long as it keeps making progress.
+// Without a stall timeout, it clears the deadline instead, so that transfers
+// are not cut off by --write-timeout.
+type stallWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	timeout time.Duration
+	cleared bool
+}
+
+func newStallWriter(w http.ResponseWriter, timeout time.Duration) *stallWriter {
+	return &stallWriter{
+		w:       w,
+		rc:      http.NewResponseController(w),
+		timeout: timeout,
+	}
+}
+
+func (s *stallWriter) Write(p []byte) (int, error) {
+	switch {
+	case s.timeout > 0:
+		err := s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return 0, err
+		}
+	case !s.cleared:
+		err := s.rc.SetWriteDeadline(time.Time{})
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return 0, err
+		}
+
+		s.cleared = true
+	}
+
+	n, err := s.w.Write(p)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		err = fmt.Errorf("%w: no progress for %s", ErrStalled, s.timeout)
+	}
+
+	return n, err
+}
@@ -0,0 +1,294 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Largest number of spans sent to the collector in one request
+	traceBatchSize = 512
+
+	// How often spans are sent to the collector
+	traceInterval = 5 * time.Second
+
+	// Spans waiting to be exported beyond this are dropped
+	traceQueueSize = 4096
+
+	// OTLP span kind and status codes
+	spanKindServer  = 2
+	spanStatusOK    = 1
+	spanStatusError = 2
+)
+
+// Span is a single traced operation, exported in the OTLP JSON encoding.
+type Span struct {
+	TraceID      string      `json:"traceId"`
+	SpanID       string      `json:"spanId"`
+	ParentSpanID string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	StartTime    string      `json:"startTimeUnixNano"`
+	EndTime      string      `json:"endTimeUnixNano"`
+	Attributes   []Attribute `json:"attributes"`
+	Status       SpanStatus  `json:"status"`
+
+	tracer *Tracer
+}
+
+type SpanStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type Attribute struct {
+	Key   string         `json:"key"`
+	Value AttributeValue `json:"value"`
+}
+
+type AttributeValue struct {
+	String *string `json:"stringValue,omitempty"`
+	Int    *string `json:"intValue,omitempty"`
+}
+
+func stringAttribute(key, value string) Attribute {
+	return Attribute{Key: key, Value: AttributeValue{String: &value}}
+}
+
+func intAttribute(key string, value int64) Attribute {
+	s := strconv.FormatInt(value, 10)
+
+	return Attribute{Key: key, Value: AttributeValue{Int: &s}}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// parseTraceparent extracts the trace and parent span IDs from a W3C
+// traceparent header, so that downloads join the caller's trace.
+func parseTraceparent(header string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+
+	_, err1 := hex.DecodeString(parts[1])
+	_, err2 := hex.DecodeString(parts[2])
+	if err1 != nil || err2 != nil {
+		return "", "", false
+	}
+
+	return parts[1], parts[2], true
+}
+
+// SetAttributes adds attributes to the span. It is safe to call on a nil
+// span, for when tracing is disabled.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+
+	s.Attributes = append(s.Attributes, attrs...)
+}
+
+// ID returns the span's trace ID, or an empty string if tracing is disabled.
+func (s *Span) ID() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.TraceID
+}
+
+// End finishes the span and queues it for export, marking it as failed if
+// err is not nil.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.EndTime = strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	s.Status.Code = spanStatusOK
+	if err != nil {
+		s.Status = SpanStatus{Code: spanStatusError, Message: redactError(err).Error()}
+	}
+
+	select {
+	case s.tracer.queue <- s:
+	default:
+	}
+}
+
+// Tracer exports spans to an OpenTelemetry collector over OTLP/HTTP, in
+// batches sent from a background goroutine.
+type Tracer struct {
+	endpoint string
+	client   *http.Client
+	queue    chan *Span
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// newTracer starts exporting to the collector at endpoint, which receives
+// spans at /v1/traces unless the endpoint already names that path.
+func newTracer(endpoint string) *Tracer {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+
+	t := &Tracer{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan *Span, traceQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go t.run()
+
+	return t
+}
+
+// Start begins a server span, continuing the trace given in the request's
+// traceparent header if there is one. It returns nil if tracing is disabled.
+func (t *Tracer) Start(r *http.Request, name string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	span := &Span{
+		TraceID:   randomHex(16),
+		SpanID:    randomHex(8),
+		Name:      name,
+		Kind:      spanKindServer,
+		StartTime: strconv.FormatInt(time.Now().UnixNano(), 10),
+		tracer:    t,
+	}
+
+	traceID, parentID, ok := parseTraceparent(r.Header.Get("Traceparent"))
+	if ok {
+		span.TraceID, span.ParentSpanID = traceID, parentID
+	}
+
+	return span
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(traceInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+
+			if len(batch) >= traceBatchSize {
+				t.export(batch)
+
+				batch = nil
+			}
+		case <-ticker.C:
+			t.export(batch)
+
+			batch = nil
+		case <-t.stop:
+			for {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+				default:
+					t.export(batch)
+
+					return
+				}
+			}
+		}
+	}
+}
+
+func (t *Tracer) export(spans []*Span) {
+	if len(spans) == 0 {
+		return
+	}
+
+	payload := map[string]any{
+		"resourceSpans": []any{
+			map[string]any{
+				"resource": map[string]any{
+					"attributes": []Attribute{
+						stringAttribute("service.name", "send"),
+						stringAttribute("service.version", ReleaseVersion),
+					},
+				},
+				"scopeSpans": []any{
+					map[string]any{
+						"scope": map[string]string{
+							"name":    "send",
+							"version": ReleaseVersion,
+						},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err == nil {
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			err = fmt.Errorf("collector responded with %s", resp.Status)
+		}
+	}
+
+	if err != nil {
+		logEvent(slog.LevelWarn, "trace_export_failed", fmt.Sprintf("Failed to export %d span(s): %s", len(spans), err),
+			"spans", len(spans),
+			"error", err.Error())
+	}
+}
+
+// Shutdown sends any spans still queued and stops exporting.
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+
+	t.once.Do(func() {
+		close(t.stop)
+
+		<-t.done
+	})
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTracerExportsDownloadSpans(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		paths    []string
+		payloads []map[string]any
+	)
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		payloads = append(payloads, payload)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	registry := newRegistry(true)
+
+	addTestFile(t, registry, &Share{Slug: "/test"}, "/f.txt", "hello")
+
+	limits := newTestLimits()
+	limits.tracer = newTracer(collector.URL)
+
+	r := httptest.NewRequest(http.MethodGet, "/test/f.txt", nil)
+	r.Header.Set("Traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	resp := serveTest(t, registry, limits, r)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.Code, http.StatusOK)
+	}
+
+	limits.tracer.Shutdown()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(paths) != 1 || paths[0] != "/v1/traces" {
+		t.Fatalf("exported to %v, want [/v1/traces]", paths)
+	}
+
+	var export struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []Span
+			}
+		}
+	}
+
+	body, _ := json.Marshal(payloads[0])
+
+	err := json.Unmarshal(body, &export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(export.ResourceSpans) != 1 || len(export.ResourceSpans[0].ScopeSpans) != 1 || len(export.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("payload does not contain exactly one span: %s", body)
+	}
+
+	span := export.ResourceSpans[0].ScopeSpans[0].Spans[0]
+
+	switch {
+	case span.Name != "download":
+		t.Errorf("name = %q, want download", span.Name)
+	case span.TraceID != "0af7651916cd43dd8448eb211c80319c":
+		t.Errorf("trace ID = %q, want the one from traceparent", span.TraceID)
+	case span.ParentSpanID != "b7ad6b7169203331":
+		t.Errorf("parent span ID = %q, want the one from traceparent", span.ParentSpanID)
+	case span.SpanID == span.ParentSpanID || len(span.SpanID) != 16:
+		t.Errorf("span ID = %q, want a new one", span.SpanID)
+	case span.Status.Code != spanStatusOK:
+		t.Errorf("status = %d, want %d", span.Status.Code, spanStatusOK)
+	}
+}
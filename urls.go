@@ -0,0 +1,138 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+)
+
+var (
+	ErrNoAddresses = errors.New("no usable addresses found for generating URLs, use --url to set one")
+)
+
+func hostURL(scheme, host string, port int) string {
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// interfaceAddresses lists the addresses of every non-loopback interface
+// which is up, or only those of the named interface if one is given.
+func interfaceAddresses(name string, ipv4Only bool) ([]net.IP, error) {
+	var interfaces []net.Interface
+
+	if name != "" {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		interfaces = append(interfaces, *iface)
+	} else {
+		all, err := net.Interfaces()
+		if err != nil {
+			return nil, err
+		}
+
+		interfaces = all
+	}
+
+	var ips []net.IP
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+
+			ip := ipNet.IP
+
+			switch {
+			case ip.IsLoopback(), ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+				continue
+			case ipv4Only && ip.To4() == nil:
+				continue
+			}
+
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips, nil
+}
+
+// baseURLs returns the scheme, host and port prefixes used to build share
+// URLs. A configured URL always wins; otherwise each TCP listener produces a
+// prefix for its address, or one per usable interface address if it is bound
+// to a wildcard address. Unix sockets are only reachable through URL.
+func baseURLs(listeners []*Listener) ([]string, error) {
+	if URL != "" {
+		return []string{URL}, nil
+	}
+
+	var bases []string
+
+	for _, l := range listeners {
+		addr, ok := l.Addr().(*net.TCPAddr)
+		if !ok {
+			continue
+		}
+
+		scheme := Scheme
+		if l.TLS {
+			scheme = "https"
+		}
+
+		if !addr.IP.IsUnspecified() {
+			bases = append(bases, hostURL(scheme, addr.IP.String(), addr.Port))
+
+			continue
+		}
+
+		ips, err := interfaceAddresses(Interface, addr.IP.To4() != nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// An interface asked for by name must provide the addresses, but a
+		// host with only loopback addresses, such as a container without
+		// networking, can still be reached locally
+		switch {
+		case len(ips) == 0 && Interface != "":
+			return nil, fmt.Errorf("%w: %s", ErrNoAddresses, Interface)
+		case len(ips) == 0:
+			ips = []net.IP{net.IPv6loopback}
+			if addr.IP.To4() != nil {
+				ips = []net.IP{net.IPv4(127, 0, 0, 1)}
+			}
+
+			logEvent(slog.LevelWarn, "no_addresses", fmt.Sprintf("No usable addresses found for %s, falling back to %s", addr, ips[0]),
+				"listen", addr.String(),
+				"fallback", ips[0].String())
+		}
+
+		for _, ip := range ips {
+			bases = append(bases, hostURL(scheme, ip.String(), addr.Port))
+		}
+	}
+
+	if len(bases) == 0 {
+		return nil, ErrNoAddresses
+	}
+
+	return bases, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// addrListener is a listener which is never accepted on, only asked for its
+// address.
+type addrListener struct {
+	net.Listener
+
+	addr net.Addr
+}
+
+func (l addrListener) Addr() net.Addr {
+	return l.addr
+}
+
+func testListener(addr net.Addr, tls bool) *Listener {
+	return &Listener{Listener: addrListener{addr: addr}, Network: addr.Network(), TLS: tls}
+}
+
+// loopbackInterface returns the name of the loopback interface, which has
+// no usable addresses.
+func loopbackInterface(t *testing.T) string {
+	t.Helper()
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			return iface.Name
+		}
+	}
+
+	t.Skip("no loopback interface")
+
+	return ""
+}
+
+func TestInterfaceAddresses(t *testing.T) {
+	_, err := interfaceAddresses("no-such-interface", false)
+	if err == nil {
+		t.Error("interfaceAddresses succeeded for an interface which does not exist")
+	}
+
+	ips, err := interfaceAddresses(loopbackInterface(t), false)
+	if err != nil || len(ips) != 0 {
+		t.Errorf("interfaceAddresses(loopback) = %v, %v, want no addresses", ips, err)
+	}
+
+	for _, ipv4Only := range []bool{false, true} {
+		ips, err := interfaceAddresses("", ipv4Only)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, ip := range ips {
+			if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ipv4Only && ip.To4() == nil {
+				t.Errorf("interfaceAddresses(%t) returned %s", ipv4Only, ip)
+			}
+		}
+	}
+}
+
+func TestBaseURLs(t *testing.T) {
+	setFlag(t, &Scheme, "http")
+	setFlag(t, &Interface, "")
+	setFlag(t, &URL, "")
+
+	bases, err := baseURLs([]*Listener{
+		testListener(&net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 8080}, false),
+		testListener(&net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 8443}, true),
+		testListener(&net.UnixAddr{Name: "/run/send.sock", Net: "unix"}, false),
+	})
+
+	want := []string{"http://192.0.2.1:8080", "https://[2001:db8::1]:8443"}
+	if err != nil || !slices.Equal(bases, want) {
+		t.Errorf("baseURLs = %v, %v, want %v", bases, err, want)
+	}
+
+	_, err = baseURLs([]*Listener{testListener(&net.UnixAddr{Name: "/run/send.sock", Net: "unix"}, false)})
+	if !errors.Is(err, ErrNoAddresses) {
+		t.Errorf("baseURLs(unix socket) error = %v, want %v", err, ErrNoAddresses)
+	}
+
+	wildcard := []*Listener{testListener(&net.TCPAddr{IP: net.IPv4zero, Port: 8080}, false)}
+
+	bases, err = baseURLs(wildcard)
+	if err != nil || len(bases) == 0 {
+		t.Errorf("baseURLs(wildcard) = %v, %v, want an address or the loopback fallback", bases, err)
+	}
+
+	for _, base := range bases {
+		if !strings.HasPrefix(base, "http://") || !strings.HasSuffix(base, ":8080") {
+			t.Errorf("baseURLs(wildcard) returned %s", base)
+		}
+	}
+
+	setFlag(t, &Interface, loopbackInterface(t))
+
+	_, err = baseURLs(wildcard)
+	if !errors.Is(err, ErrNoAddresses) {
+		t.Errorf("baseURLs(wildcard on loopback) error = %v, want %v", err, ErrNoAddresses)
+	}
+
+	setFlag(t, &URL, "https://send.example.com")
+
+	bases, err = baseURLs(wildcard)
+	if err != nil || !slices.Equal(bases, []string{"https://send.example.com"}) {
+		t.Errorf("baseURLs with --url = %v, %v", bases, err)
+	}
+}
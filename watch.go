@@ -0,0 +1,385 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// A file created this soon after another in the directory was renamed
+	// is taken to be the other end of that rename, and so already complete
+	watchRenameWindow = 100 * time.Millisecond
+)
+
+var (
+	ErrInvalidWatchSettle = errors.New("watch settle time must be a positive duration")
+)
+
+// pendingFile is a file in a watched directory which may still be being
+// written.
+type pendingFile struct {
+	size  int64
+	mod   time.Time
+	timer *time.Timer
+}
+
+// DirWatcher publishes each file written into a directory as a share of its
+// own, and revokes the share when the file is removed.
+type DirWatcher struct {
+	dir      string
+	settle   time.Duration
+	registry *Registry
+	limits   *Limits
+	bases    []string
+	watcher  *fsnotify.Watcher
+
+	mu      sync.Mutex
+	pending map[string]*pendingFile
+	renamed time.Time
+	errs    []error
+
+	// Slugs of the shares published for each file, which may be revoked
+	// while mu is held
+	sharesMu sync.Mutex
+	shares   map[string]string
+
+	errorChannel chan<- Error
+}
+
+// isTemporaryFile reports whether a file name is one commonly used while a
+// file is being written, before it is renamed into place.
+func isTemporaryFile(name string) bool {
+	return strings.HasPrefix(name, ".") ||
+		strings.HasSuffix(name, "~") ||
+		strings.HasSuffix(name, ".tmp") ||
+		strings.HasSuffix(name, ".part") ||
+		strings.HasSuffix(name, ".swp")
+}
+
+// watchDirectory publishes the files already in dir, and any written to it
+// from now on.
+func watchDirectory(dir string, registry *Registry, limits *Limits, bases []string, errorChannel chan<- Error) (*DirWatcher, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	err = watcher.Add(dir)
+	if err != nil {
+		watcher.Close()
+
+		return nil, err
+	}
+
+	w := &DirWatcher{
+		dir:          dir,
+		settle:       WatchSettle,
+		registry:     registry,
+		limits:       limits,
+		bases:        bases,
+		watcher:      watcher,
+		pending:      make(map[string]*pendingFile),
+		shares:       make(map[string]string),
+		errorChannel: errorChannel,
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		watcher.Close()
+
+		return nil, err
+	}
+
+	registry.onRemove(w.removed)
+
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !isTemporaryFile(entry.Name()) {
+			w.check(filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *DirWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			w.handle(event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			w.errorChannel <- Error{Message: fmt.Errorf("watching %s: %w", w.dir, err)}
+		}
+	}
+}
+
+// unlock releases mu, then reports any errors recorded while it was held,
+// since whatever receives them may itself need the watcher.
+func (w *DirWatcher) unlock() {
+	errs := w.errs
+
+	w.errs = nil
+
+	w.mu.Unlock()
+
+	for _, err := range errs {
+		w.errorChannel <- Error{Message: err}
+	}
+}
+
+func (w *DirWatcher) handle(event fsnotify.Event) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	if isTemporaryFile(filepath.Base(event.Name)) {
+		if event.Has(fsnotify.Rename) {
+			w.renamed = time.Now()
+		}
+
+		w.forget(event.Name)
+
+		return
+	}
+
+	switch {
+	case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+		if event.Has(fsnotify.Rename) {
+			w.renamed = time.Now()
+		}
+
+		w.forget(event.Name)
+
+		w.unpublish(event.Name)
+	case event.Has(fsnotify.Create) && time.Since(w.renamed) < watchRenameWindow:
+		w.forget(event.Name)
+
+		w.publish(event.Name)
+	case event.Has(fsnotify.Create) || event.Has(fsnotify.Write):
+		w.wait(event.Name)
+	}
+}
+
+// wait records the current size and modification time of a file, and
+// checks again once it has had time to settle.
+func (w *DirWatcher) wait(path string) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+
+	p, ok := w.pending[path]
+	if !ok {
+		p = &pendingFile{}
+
+		p.timer = time.AfterFunc(w.settle, func() {
+			w.mu.Lock()
+			defer w.unlock()
+
+			w.settled(path)
+		})
+
+		w.pending[path] = p
+	} else {
+		p.timer.Reset(w.settle)
+	}
+
+	p.size, p.mod = info.Size(), info.ModTime()
+}
+
+// check publishes a file found when the watch started, once it has settled.
+func (w *DirWatcher) check(path string) {
+	w.mu.Lock()
+	defer w.unlock()
+
+	w.wait(path)
+}
+
+// settled publishes a file if it has not changed since it was last seen,
+// and otherwise keeps waiting.
+func (w *DirWatcher) settled(path string) {
+	p, ok := w.pending[path]
+	if !ok {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		delete(w.pending, path)
+
+		return
+	}
+
+	if info.Size() != p.size || !info.ModTime().Equal(p.mod) {
+		w.wait(path)
+
+		return
+	}
+
+	delete(w.pending, path)
+
+	w.publish(path)
+}
+
+func (w *DirWatcher) forget(path string) {
+	p, ok := w.pending[path]
+	if ok {
+		p.timer.Stop()
+
+		delete(w.pending, path)
+	}
+}
+
+func (w *DirWatcher) publish(path string) {
+	w.sharesMu.Lock()
+	slug, ok := w.shares[path]
+	w.sharesMu.Unlock()
+
+	// A file rewritten in place keeps its share, until that is used up
+	if ok && w.registry.lookupShare(slug) != nil {
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return
+	}
+
+	now := time.Now()
+
+	share := &Share{
+		Slug:      "/" + generateRandomString(Length),
+		Count:     Count,
+		Randomize: Randomize,
+		Burn:      Burn,
+		Live:      LiveFile,
+		origin:    "watch: " + path,
+	}
+
+	share.Expire, _ = parseTime(Expire, now)
+	share.NotBefore, _ = parseTime(NotBefore, now)
+
+	// Files restored from the state directory keep their existing share
+	if restored := w.registry.lookupOrigin(share.origin); restored != nil {
+		w.setShare(path, restored.Slug)
+
+		return
+	}
+
+	err = w.registry.add(share)
+	if err != nil {
+		w.errs = append(w.errs, err)
+
+		return
+	}
+
+	urls, _, err := registerHandler(w.registry, path, share, w.bases)
+	if err != nil {
+		w.registry.remove(share)
+
+		w.errs = append(w.errs, err)
+
+		return
+	}
+
+	w.setShare(path, share.Slug)
+
+	for _, url := range urls {
+		logEvent(slog.LevelInfo, "share_registered", fmt.Sprintf("%s -> %s", url, path),
+			"slug", share.Slug,
+			"url", url,
+			"path", path,
+			"source", "watch")
+	}
+
+	w.limits.emit(shareEvent("share_created", "watch", share, w.bases))
+
+	w.limits.mailer.queueShare(shareStatus(share, w.bases, now))
+}
+
+func (w *DirWatcher) unpublish(path string) {
+	w.sharesMu.Lock()
+
+	slug, ok := w.shares[path]
+
+	delete(w.shares, path)
+
+	w.sharesMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	_, err := w.registry.revoke(slug)
+	if err != nil {
+		return
+	}
+
+	logEvent(slog.LevelInfo, "share_revoked", fmt.Sprintf("Revoked %s, since %s was removed", slug, path),
+		"slug", slug,
+		"path", path,
+		"source", "watch")
+
+	w.limits.emit(&Event{Type: "share_revoked", Source: "watch", Slug: slug, Path: path})
+}
+
+func (w *DirWatcher) setShare(path, slug string) {
+	w.sharesMu.Lock()
+	defer w.sharesMu.Unlock()
+
+	w.shares[path] = slug
+}
+
+// removed forgets the file behind a share once it is revoked, whether by
+// the watcher or through the admin API.
+func (w *DirWatcher) removed(s *Share) {
+	w.sharesMu.Lock()
+	defer w.sharesMu.Unlock()
+
+	for path, slug := range w.shares {
+		if slug == s.Slug {
+			delete(w.shares, path)
+		}
+	}
+}
+
+func (w *DirWatcher) Close() error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+
+	for path := range w.pending {
+		w.forget(path)
+	}
+
+	w.mu.Unlock()
+
+	return w.watcher.Close()
+}
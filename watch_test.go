@@ -0,0 +1,119 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForShare polls until the file at path is published, or no longer is.
+func waitForShare(t *testing.T, registry *Registry, path string, published bool) *Share {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		share := registry.lookupOrigin("watch: " + path)
+		if (share != nil) == published {
+			return share
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("%s published = %t, want %t", path, share != nil, published)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchDirectory(t *testing.T) {
+	setFlag(t, &Count, 0)
+	setFlag(t, &Length, 6)
+	setFlag(t, &WatchSettle, 50*time.Millisecond)
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errorChannel := make(chan Error, 10)
+
+	registry := newRegistry(true)
+
+	w, err := watchDirectory(dir, registry, newTestLimits(), []string{"http://host"}, errorChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	first := filepath.Join(dir, "first.txt")
+
+	err = os.WriteFile(first, []byte("hello"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	share := waitForShare(t, registry, first, true)
+
+	// Renaming within the directory moves the file to a new share
+	second := filepath.Join(dir, "second.txt")
+
+	err = os.Rename(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitForShare(t, registry, first, false)
+
+	if registry.lookupShare(share.Slug) != nil {
+		t.Errorf("share %s of the renamed file is still registered", share.Slug)
+	}
+
+	share = waitForShare(t, registry, second, true)
+
+	// Temporary files are only published once renamed into place
+	partial := filepath.Join(dir, "third.txt.part")
+
+	err = os.WriteFile(partial, []byte("partial"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	third := filepath.Join(dir, "third.txt")
+
+	err = os.Rename(partial, third)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitForShare(t, registry, third, true)
+
+	if registry.lookupOrigin("watch: "+partial) != nil {
+		t.Error("temporary file was published")
+	}
+
+	// A share revoked elsewhere is forgotten by the watcher
+	_, err = registry.revoke(share.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.sharesMu.Lock()
+	_, ok := w.shares[second]
+	w.sharesMu.Unlock()
+
+	if ok {
+		t.Errorf("watcher still tracks %s after its share was revoked", second)
+	}
+
+	select {
+	case err := <-errorChannel:
+		t.Errorf("unexpected error: %v", err.Message)
+	default:
+	}
+}
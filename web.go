@@ -6,29 +6,37 @@ package main
 
 import (
 	"bufio"
-	"context"
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"math/rand/v2"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
 var (
-	ErrInvalidCount     = errors.New("count must be a non-negative integer")
-	ErrInvalidLength    = errors.New("length must be a non-negative integer")
-	ErrInvalidPort      = errors.New("listen port must be an integer between 1 and 65535 inclusive")
-	ErrInvalidTimeout   = errors.New("timeout interval must be longer than timeout")
-	ErrInvalidTLSConfig = errors.New("TLS certificate and keyfile must both be specified to enable HTTPS")
-	ErrNoFile           = errors.New("no files specified and no data received from stdin")
+	ErrInvalidCount        = errors.New("count must be a non-negative integer")
+	ErrInvalidDrainTimeout = errors.New("drain timeout must be a non-negative duration")
+	ErrInvalidIdle         = errors.New("idle timeout must be a non-negative duration")
+	ErrInvalidLength       = errors.New("length must be a non-negative integer")
+	ErrInvalidPort         = errors.New("listen port must be an integer between 0 and 65535 inclusive, auto, or a range such as 8080-8099")
+	ErrInvalidTimeout      = errors.New("timeout interval must be longer than timeout")
+	ErrInvalidTLSConfig    = errors.New("TLS certificate and keyfile must both be specified to enable HTTPS")
+	ErrInvalidName         = errors.New("name must not contain slashes")
+	ErrNoFile              = errors.New("no files specified and no data received from stdin")
 )
 
 const (
@@ -40,8 +48,18 @@ const (
 )
 
 type Limits struct {
-	channel chan bool
-	counter *uint32
+	access    *AccessLog
+	audit     *AuditLog
+	draining  atomic.Bool
+	hooks     *Hooks
+	idle      *IdleTimer
+	mailer    *Mailer
+	metrics   *Collector
+	runner    *Runner
+	throttle  *Limiter
+	tracer    *Tracer
+	transfers *Transfers
+	webhooks  *Webhooks
 }
 
 type Error struct {
@@ -50,6 +68,12 @@ type Error struct {
 	Fatal   bool
 }
 
+// createdShare is a share created at startup, and where it was defined.
+type createdShare struct {
+	share  *Share
+	source string
+}
+
 func securityHeaders(w http.ResponseWriter) {
 	w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
 	w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
@@ -94,20 +118,6 @@ func generateRandomString(length int) string {
 	return builder.String()
 }
 
-func updateCounter(limits *Limits) string {
-	atomic.AddUint32(limits.counter, 1)
-	counter := atomic.LoadUint32(limits.counter)
-	if counter >= uint32(Count) {
-		defer func() {
-			limits.channel <- true
-		}()
-	}
-
-	remaining := Count - int(counter)
-
-	return fmt.Sprintf(" (%d remaining)", remaining)
-}
-
 func readStdin() ([]byte, error) {
 	var response []byte
 
@@ -164,114 +174,474 @@ func realIP(r *http.Request, includePort bool) string {
 	}
 }
 
-func serveResponse(w http.ResponseWriter, r http.Request, response []byte, fullpath string, limits *Limits) error {
-	remaining := ""
+// clientIP returns the address used for access control. Forwarding headers
+// are only trusted from local connections, such as a reverse proxy on the
+// same host or a Unix socket, since anyone else could set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err == nil {
+		ip := net.ParseIP(host)
+		if ip != nil && !ip.IsLoopback() {
+			return host
+		}
+	}
+
+	return realIP(r, false)
+}
+
+func serveResponse(w http.ResponseWriter, r http.Request, share *Share, file *File, limits *Limits) error {
+	now := time.Now()
+
+	if !share.allowed(clientIP(&r)) {
+		client := loggedIP(clientIP(&r))
+
+		limits.metrics.deny("not_allowed", client)
 
-	if Count != 0 {
-		remaining = updateCounter(limits)
+		logEvent(slog.LevelWarn, "download_denied", fmt.Sprintf("Refused %s to %s, which is not on the allowlist", r.URL.Path, client),
+			"slug", share.Slug,
+			"path", r.URL.Path,
+			"client", client)
+
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+		return nil
 	}
 
-	fmt.Printf("%s | %s => %s%s\n", time.Now().Format(logDate), fullpath, realIP(&r, true), remaining)
+	if !share.authorized(&r) {
+		limits.metrics.deny("unauthorized", loggedIP(clientIP(&r)))
 
-	w.Header().Set("Content-Type", http.DetectContentType(response))
+		w.Header().Set("WWW-Authenticate", `Basic realm="send", charset="UTF-8"`)
 
-	w.Header().Set("Content-Length", strconv.Itoa(len(response)))
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 
-	securityHeaders(w)
+		return nil
+	}
+
+	if share.pending(now) {
+		http.NotFound(w, &r)
+
+		return nil
+	}
 
-	_, err := w.Write(response)
+	if file.live {
+		answered, err := checkLive(w, &r, file)
+		if answered {
+			return err
+		}
+	}
+
+	if file.command != "" {
+		if !limits.runner.acquire() {
+			logEvent(slog.LevelInfo, "download_deferred", fmt.Sprintf("Not running %s, since the limit of %d at once has been reached", file.command, ExecConcurrency),
+				"path", file.Path,
+				"client", loggedIP(realIP(&r, true)))
+
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+
+			return nil
+		}
+		defer limits.runner.release()
+	}
+
+	// A download is only counted once its content has been opened, so that
+	// requests for an expired or used up share, and failures to open the
+	// content, do not use up the share
+	if share.expired(now) || share.exhausted() {
+		http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+
+		return nil
+	}
+
+	// The idle timeout counts from the end of the last download, so that a
+	// slow one is not cut off
+	limits.idle.Pause()
+	defer limits.idle.Resume()
+
+	client := loggedIP(realIP(&r, true))
+
+	span := limits.tracer.Start(&r, "download")
+
+	span.SetAttributes(
+		stringAttribute("send.share.slug", share.Slug),
+		stringAttribute("send.file.path", file.Path),
+		stringAttribute("url.path", r.URL.Path),
+		stringAttribute("client.address", loggedIP(realIP(&r, false))),
+		intAttribute("send.file.size", file.Size()))
+
+	var traceAttrs []any
+	if span != nil {
+		traceAttrs = append(traceAttrs, "trace_id", span.ID())
+	}
+
+	rc := http.NewResponseController(w)
+
+	body, err := file.open(&r, func() { rc.Flush() })
 	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		span.End(err)
+
 		return err
 	}
+	defer body.Close()
 
-	return nil
-}
+	for key, values := range body.Header {
+		w.Header()[key] = values
+	}
 
-func serveResponseHandler(response []byte, fullpath string, limits *Limits, errorChannel chan<- Error) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		err := serveResponse(w, *r, response, fullpath, limits)
-		if err != nil {
-			errorChannel <- Error{Message: err, Host: realIP(r, true)}
+	// A range which storage cannot satisfy sends nothing, so is not counted
+	if body.Status == http.StatusRequestedRangeNotSatisfiable {
+		securityHeaders(w)
+
+		w.WriteHeader(body.Status)
+
+		span.End(nil)
+
+		return nil
+	}
+
+	left, ok := share.reserve()
+	if !ok {
+		for key := range body.Header {
+			w.Header().Del(key)
 		}
+
+		http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
+
+		span.End(nil)
+
+		return nil
 	}
-}
 
-func registerHandler(mux *httprouter.Router, path, slug string, limits *Limits, errorChannel chan<- Error) (url, fullpath string) {
-	var filename string
+	remaining := ""
 
-	switch {
-	case Randomize || path == "":
-		filename = "/" + generateRandomString(Length)
-	default:
-		filename = "/" + filepath.Base(path)
+	if left >= 0 {
+		remaining = fmt.Sprintf(" (%d remaining)", left)
 	}
 
-	var response []byte
-	var err error
+	fullpath := file.Path
+
+	logEvent(slog.LevelInfo, "download_started",
+		fmt.Sprintf("%s => %s%s", fullpath, client, remaining),
+		append([]any{
+			"slug", share.Slug,
+			"url_path", share.Slug + file.Name,
+			"path", fullpath,
+			"client", client,
+			"remaining", left,
+		}, traceAttrs...)...)
+
+	started := &Event{
+		Type:   "download_started",
+		Slug:   share.Slug,
+		Path:   fullpath,
+		Client: client,
+	}
 
-	if path == "" {
-		response, err = readStdin()
+	if left >= 0 {
+		started.Remaining = &left
+	}
+
+	limits.emit(started)
+
+	if w.Header().Get("Content-Type") == "" {
+		var contentType string
+
+		contentType, body.ReadCloser, err = sniff(body.ReadCloser)
 		if err != nil {
-			errorChannel <- Error{Message: err}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 
-			return "", ""
+			span.End(err)
+
+			return err
 		}
 
-		fullpath = "<data from stdin>"
-	} else {
-		f, err := os.Stat(path)
-		if err != nil {
-			errorChannel <- Error{Message: err}
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	if body.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(body.Size, 10))
+	}
+
+	if file.command != "" {
+		w.Header().Set("Trailer", commandStatusTrailer)
+	}
 
-			return "", ""
+	securityHeaders(w)
+
+	for key, value := range share.Headers {
+		w.Header().Set(key, value)
+	}
+
+	if body.Status != 0 {
+		w.WriteHeader(body.Status)
+	}
+
+	transfer := limits.transfers.begin(fullpath, client, body.Size, newStallWriter(w, StallTimeout))
+
+	// Clients are told apart by their trusted address, so that forwarding
+	// headers cannot be used to get a fresh bucket on every request
+	writer := limits.throttle.Writer(r.Context(), transfer, clientIP(&r))
+	defer writer.Close()
+
+	_, err = io.Copy(writer, body)
+
+	if file.command != "" {
+		w.Header().Set(commandStatusTrailer, commandStatus(err))
+
+		if errors.Is(err, ErrCommandFailed) && !acceptsTrailers(&r) {
+			err = fmt.Errorf("%w (%w)", err, ErrResponseTruncated)
 		}
-		if f.IsDir() {
-			return "", ""
+	}
+
+	// The transfer ends only once its events have been emitted, so that
+	// draining waits for any hooks they start
+	defer limits.transfers.end(transfer, err)
+
+	limits.metrics.transfer(transfer, err == nil)
+
+	span.SetAttributes(intAttribute("send.bytes", transfer.Written()))
+
+	span.End(err)
+
+	// The share is exhausted once its last download has ended, whether or
+	// not that download succeeded
+	if left == 0 {
+		limits.emit(&Event{Type: "share_exhausted", Slug: share.Slug, Path: fullpath, Count: share.Count})
+	}
+
+	if err != nil {
+		if left == 0 && share.Burn {
+			logEvent(slog.LevelWarn, "burn_skipped", fmt.Sprintf("Not burning files of %s, since its final download failed", share.Slug),
+				"slug", share.Slug)
 		}
 
-		fullpath, err = filepath.Abs(path)
+		return err
+	}
+
+	logEvent(slog.LevelInfo, "download_completed",
+		fmt.Sprintf("%s => %s completed (%s)", fullpath, client, formatThroughput(transfer.Written(), time.Since(transfer.Started))),
+		append(append(transfer.attrs(), "slug", share.Slug), traceAttrs...)...)
+
+	limits.emit(&Event{
+		Type:      "download_completed",
+		Slug:      share.Slug,
+		Path:      transfer.Path,
+		Client:    transfer.Client,
+		Bytes:     transfer.Written(),
+		Duration:  time.Since(transfer.Started).Seconds(),
+		Remaining: started.Remaining,
+	})
+
+	if left == 0 && share.Burn {
+		return burn(share, limits)
+	}
+
+	return nil
+}
+
+// sniff detects the content type of body from whatever its first read
+// returns, without waiting for more, and returns a reader which still
+// includes that data.
+func sniff(body io.ReadCloser) (string, io.ReadCloser, error) {
+	buf := make([]byte, 512)
+
+	n, err := body.Read(buf)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", nil, err
+	}
+
+	return http.DetectContentType(buf[:n]), struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf[:n]), body), body}, nil
+}
+
+// shareHandler serves files from the registry, looking them up by path on
+// every request so that shares can be added or revoked at any time.
+func shareHandler(registry *Registry, limits *Limits, errorChannel chan<- Error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		share, file := registry.lookup(r.URL.Path)
+		if share == nil {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		err := serveResponse(w, *r, share, file, limits)
 		if err != nil {
-			errorChannel <- Error{Message: err}
+			errorChannel <- Error{Message: redactError(err), Host: loggedIP(realIP(r, true))}
+		}
 
-			return "", ""
+		// Closing the connection before the end of the response shows clients
+		// which would not see the failure reported in a trailer that it is
+		// incomplete
+		if errors.Is(err, ErrResponseTruncated) {
+			panic(http.ErrAbortHandler)
 		}
+	})
+}
 
-		response, err = readFile(path)
+// fileName returns the filename component of the URL for a file read from
+// path, or from stdin if path is empty.
+func fileName(share *Share, path string) string {
+	if share.Randomize || path == "" {
+		return "/" + generateRandomString(Length)
+	}
+
+	return "/" + filepath.Base(path)
+}
+
+// loadFile reads the file at path, or stdin if path is empty. Directories
+// are skipped, returning no path and no error. Live files are only checked,
+// since they are read on every request instead.
+func loadFile(path string, live bool) (fullpath string, content []byte, err error) {
+	if path == "" {
+		content, err = readStdin()
 		if err != nil {
-			errorChannel <- Error{Message: err}
+			return "", nil, err
+		}
+
+		return "<data from stdin>", content, nil
+	}
 
-			return "", ""
+	f, err := os.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if f.IsDir() {
+		return "", nil, nil
+	}
+
+	fullpath, err = filepath.Abs(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if live {
+		return fullpath, nil, nil
+	}
+
+	content, err = readFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return fullpath, content, nil
+}
+
+// absolutePaths resolves paths against the working directory, leaving any
+// which cannot be resolved, and S3 URLs, unchanged.
+func absolutePaths(paths []string) []string {
+	resolved := make([]string, len(paths))
+
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil || isS3URL(path) {
+			abs = path
 		}
+
+		resolved[i] = abs
 	}
 
-	mux.GET(fmt.Sprintf("%s%s", slug, filename), serveResponseHandler(response, fullpath, limits, errorChannel))
+	return resolved
+}
 
-	switch {
-	case URL != "":
-		return fmt.Sprintf("%s%s%s", URL, slug, filename), fullpath
-	default:
-		return fmt.Sprintf("%s://%s:%d%s%s", Scheme, Bind, Port, slug, filename), fullpath
+func fileURLs(bases []string, share *Share, file *File) []string {
+	urls := make([]string, 0, len(bases))
+
+	for _, base := range bases {
+		urls = append(urls, base+share.Slug+file.Name)
 	}
+
+	return urls
 }
 
-func registerHandlers(mux *httprouter.Router, args []string, slug string, limits *Limits, errorChannel chan<- Error) (urls, paths []string) {
-	if len(args) == 0 && !isFromPipe() {
+// publishFile adds a file to an already registered share, returning the URLs
+// at which it can be downloaded.
+func publishFile(registry *Registry, share *Share, file *File, bases []string) ([]string, error) {
+	err := registry.addFile(share, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileURLs(bases, share, file), nil
+}
+
+func registerHandler(registry *Registry, path string, share *Share, bases []string) (urls []string, fullpath string, err error) {
+	if isS3URL(path) {
+		return registerObject(registry, path, share, bases)
+	}
+
+	live := share.Live && path != ""
+
+	fullpath, content, err := loadFile(path, live)
+	if err != nil || fullpath == "" {
+		return nil, "", err
+	}
+
+	file := &File{
+		Name:    fileName(share, path),
+		Path:    fullpath,
+		content: content,
+		inline:  path == "",
+		live:    live,
+	}
+
+	if path == "" {
+		file.Name = commandName(share)
+	}
+
+	urls, err = publishFile(registry, share, file, bases)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return urls, fullpath, nil
+}
+
+func registerHandlers(registry *Registry, args []string, stdin bool, command string, share *Share, bases []string, errorChannel chan<- Error) (urls, paths []string) {
+	if len(args) == 0 && !stdin && command == "" {
 		errorChannel <- Error{Message: ErrNoFile}
 
 		return urls, paths
 	}
 
-	if isFromPipe() {
-		url, path := registerHandler(mux, "", slug, limits, errorChannel)
-		if url != "" {
+	if stdin {
+		args = append([]string{""}, args...)
+	}
+
+	for i := range args {
+		fileURLs, path, err := registerHandler(registry, args[i], share, bases)
+		if err != nil {
+			errorChannel <- Error{Message: err}
+
+			continue
+		}
+
+		for _, url := range fileURLs {
 			urls = append(urls, url)
 			paths = append(paths, path)
 		}
 	}
 
-	for i := range args {
-		url, path := registerHandler(mux, args[i], slug, limits, errorChannel)
-		if url != "" {
+	if command != "" {
+		fileURLs, path, err := registerCommand(registry, command, share, bases)
+		if err != nil {
+			errorChannel <- Error{Message: err}
+
+			return urls, paths
+		}
+
+		for _, url := range fileURLs {
 			urls = append(urls, url)
 			paths = append(paths, path)
 		}
@@ -280,116 +650,522 @@ func registerHandlers(mux *httprouter.Router, args []string, slug string, limits
 	return urls, paths
 }
 
+// registerShare adds the share to the registry and registers handlers for
+// its files, dropping it again if none of them could be registered.
+func registerShare(registry *Registry, share *Share, args []string, stdin bool, command string, bases []string, errorChannel chan<- Error) (urls, paths []string, err error) {
+	err = registry.add(share)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	urls, paths = registerHandlers(registry, args, stdin, command, share, bases, errorChannel)
+
+	if len(share.Files) == 0 {
+		registry.remove(share)
+	}
+
+	return urls, paths, nil
+}
+
+func printCountdown(deadline time.Time, registry *Registry, limits *Limits) {
+	if Timeout != 0 {
+		remains := time.Until(deadline).Round(time.Second)
+
+		if remains > 0 {
+			logEvent(slog.LevelInfo, "shutdown_pending", fmt.Sprintf("Shutdown in %s", remains),
+				"reason", "timeout",
+				"remaining_seconds", remains.Seconds())
+		}
+	}
+
+	if Idle != 0 {
+		remains := limits.idle.Remaining().Round(time.Second)
+
+		if remains > 0 {
+			logEvent(slog.LevelInfo, "shutdown_pending", fmt.Sprintf("Idle shutdown in %s", remains),
+				"reason", "idle",
+				"remaining_seconds", remains.Seconds())
+		}
+	}
+
+	share, next := registry.nextExpiry()
+	if share != nil {
+		remains := time.Until(next).Round(time.Second)
+
+		logEvent(slog.LevelInfo, "expiry_pending", fmt.Sprintf("Next expiry in %s (%s)", remains, share.Slug),
+			"slug", share.Slug,
+			"remaining_seconds", remains.Seconds())
+	}
+}
+
 func ServePage(args []string) error {
 	startTime := time.Now()
 
 	mux := httprouter.New()
 
+	rate, err := parseRate(Rate)
+	if err != nil {
+		return err
+	}
+
+	ratePerClient, err := parseRate(RatePerClient)
+	if err != nil {
+		return err
+	}
+
+	var tracer *Tracer
+
+	if OTLPEndpoint != "" {
+		tracer = newTracer(OTLPEndpoint)
+		defer tracer.Shutdown()
+	}
+
+	var webhooks *Webhooks
+
+	if len(WebhookURLs) > 0 {
+		webhooks = newWebhooks(WebhookURLs, WebhookSecret)
+		defer webhooks.Shutdown()
+	}
+
+	limits := &Limits{
+		metrics:   newCollector(),
+		runner:    newRunner(ExecConcurrency),
+		throttle:  newLimiter(rate, ratePerClient),
+		tracer:    tracer,
+		transfers: newTransfers(),
+		webhooks:  webhooks,
+	}
+
+	registry := newRegistry(Admin != "" || Daemon || len(Watch) > 0)
+
 	srv := &http.Server{
-		Addr:         net.JoinHostPort(Bind, strconv.Itoa(Port)),
-		Handler:      mux,
-		IdleTimeout:  10 * time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Minute,
+		Handler:           recordRequests(drainHandler(mux, limits), limits),
+		IdleTimeout:       KeepAliveTimeout,
+		ReadHeaderTimeout: ReadHeaderTimeout,
+		ReadTimeout:       ReadTimeout,
+		WriteTimeout:      WriteTimeout,
 	}
 
 	errorChannel := make(chan Error)
 
+	limits.hooks = newHooks(OnDownload, OnExhausted, HookTimeout, errorChannel)
+
+	limits.mailer, err = newMailer(errorChannel)
+	if err != nil {
+		return err
+	}
+
+	mux.NotFound = shareHandler(registry, limits, errorChannel)
+
+	var shutdownOnce sync.Once
+
+	drained := make(chan struct{})
+
+	var shutdownReason string
+
+	shutdown := func(reason string) {
+		shutdownOnce.Do(func() {
+			shutdownReason = reason
+
+			go func() {
+				defer close(drained)
+
+				err := drain(srv, limits, reason)
+				if err != nil {
+					errorChannel <- Error{Message: err}
+				}
+			}()
+		})
+	}
+
 	go func() {
 		for err := range errorChannel {
+			// Sent only to wait until earlier errors have been printed
+			if err.Message == nil {
+				continue
+			}
+
+			err.Message = redactError(err.Message)
+
+			limits.metrics.error(err.Message)
+
 			if err.Host == "" {
-				fmt.Printf("%s | Error: %s\n",
-					time.Now().Format(logDate),
-					err.Message)
+				logEvent(slog.LevelError, "error", fmt.Sprintf("Error: %s", err.Message),
+					"error", err.Message.Error(),
+					"fatal", err.Fatal)
 			} else {
-				fmt.Printf("%s | Error: %s (<= %s)\n",
-					time.Now().Format(logDate),
-					err.Message,
-					err.Host)
+				logEvent(slog.LevelError, "error", fmt.Sprintf("Error: %s (<= %s)", err.Message, err.Host),
+					"error", err.Message.Error(),
+					"client", err.Host,
+					"fatal", err.Fatal)
 			}
 
 			if ErrorExit || err.Fatal {
-				srv.Shutdown(context.Background())
-
-				break
+				shutdown("Exiting on error")
 			}
 		}
 	}()
 
-	limits := &Limits{
-		channel: make(chan bool, 1),
-		counter: new(uint32),
-	}
+	signals := make(chan os.Signal, 1)
+
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		<-limits.channel
+		sig := <-signals
+
+		shutdown(fmt.Sprintf("Received %s", sig))
+
+		<-signals
+
+		srv.Close()
+	}()
+
+	if RateControl != "" && len(rateSignals) > 0 {
+		signals := make(chan os.Signal, 1)
+
+		signal.Notify(signals, rateSignals...)
 
-		err := srv.Shutdown(context.Background())
+		go func() {
+			for range signals {
+				rate, ratePerClient := limits.throttle.Rates()
 
-		errorChannel <- Error{Message: err}
+				rate, ratePerClient, err := readRateControl(RateControl, rate, ratePerClient)
+				if err != nil {
+					errorChannel <- Error{Message: err}
+
+					continue
+				}
+
+				limits.throttle.Set(rate, ratePerClient)
+
+				logRates(rate, ratePerClient, "")
+			}
+		}()
+	}
+
+	if AccessLogFile != "" {
+		limits.access, err = openAccessLog(errorChannel)
+		if err != nil {
+			return err
+		}
+		defer limits.access.Close()
+	}
+
+	if AuditLogFile != "" {
+		limits.audit, err = openAuditLog(AuditLogFile, AuditKey, AuditSignInterval, errorChannel)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err := limits.audit.Close()
+			if err != nil {
+				logEvent(slog.LevelError, "error", fmt.Sprintf("Error: %s", err), "error", err.Error())
+			}
+		}()
+	}
+
+	if AccessLogFile != "" && len(reopenSignals) > 0 {
+		signals := make(chan os.Signal, 1)
+
+		signal.Notify(signals, reopenSignals...)
+
+		go func() {
+			for range signals {
+				err := limits.access.Reopen()
+				if err != nil {
+					errorChannel <- Error{Message: err}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		<-registry.done
+
+		shutdown("All shares have expired or been used up")
 	}()
 
 	if Profile {
 		registerProfileHandlers(mux)
 	}
 
-	urls, paths := registerHandlers(mux, args, "/"+generateRandomString(Length), limits, errorChannel)
-	if len(urls) == 0 || len(paths) == 0 {
+	if Metrics {
+		mux.Handler("GET", "/metrics", metricsHandler(registry, limits))
+	}
+
+	expire, err := parseTime(Expire, startTime)
+	if err != nil {
+		return err
+	}
+
+	notBefore, err := parseTime(NotBefore, startTime)
+	if err != nil {
+		return err
+	}
+
+	manifest := &Manifest{}
+
+	if ManifestFile != "" {
+		manifest, err = loadManifest(ManifestFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifestShares := make([]*Share, len(manifest.Shares))
+
+	for i := range manifest.Shares {
+		manifestShares[i], err = manifest.Shares[i].newShare(startTime)
+		if err != nil {
+			return fmt.Errorf("%s: share %d: %w", ManifestFile, i+1, err)
+		}
+	}
+
+	listeners, err := openListeners(Listen)
+	if err != nil {
+		return err
+	}
+
+	bases, err := baseURLs(listeners)
+	if err != nil {
+		closeListeners(listeners)
+
+		return err
+	}
+
+	adminServers, err := openAdminServers(registry, limits, bases)
+	if err != nil {
+		closeListeners(listeners)
+
+		return err
+	}
+
+	stdin := isFromPipe() && !Daemon
+
+	var urls, paths []string
+
+	// Shares created by this run, rather than restored from the state
+	// directory
+	var created []createdShare
+
+	deadline := startTime.Add(Timeout)
+
+	if StateDir != "" {
+		store, state, err := openStateStore(StateDir, errorChannel)
+		if err != nil {
+			closeListeners(listeners)
+
+			return err
+		}
+
+		if Timeout != 0 && state.Timeout == Timeout && !state.Deadline.IsZero() {
+			deadline = state.Deadline
+		}
+
+		if Timeout != 0 {
+			store.deadline = deadline
+		}
+
+		urls, paths = store.restore(state, registry, bases)
+
+		registry.state = store
+
+		registry.save()
+	}
+
+	if len(args) > 0 || stdin || Exec != "" {
+		share := &Share{
+			Slug:      "/" + generateRandomString(Length),
+			Count:     Count,
+			Expire:    expire,
+			NotBefore: notBefore,
+			Randomize: Randomize,
+			Burn:      Burn,
+			Live:      LiveFile,
+		}
+
+		// Data read from stdin is new on every run, so is never matched
+		// against restored shares
+		if !stdin {
+			sources := absolutePaths(args)
+			if Exec != "" {
+				sources = append(sources, "--exec "+Exec)
+			}
+
+			share.origin = "command line: " + strings.Join(sources, " ")
+		}
+
+		if share.origin == "" || registry.lookupOrigin(share.origin) == nil {
+			shareURLs, sharePaths, err := registerShare(registry, share, args, stdin, Exec, bases, errorChannel)
+			if err != nil {
+				closeListeners(listeners)
+
+				return err
+			}
+
+			urls = append(urls, shareURLs...)
+			paths = append(paths, sharePaths...)
+
+			created = append(created, createdShare{share, "command line"})
+		}
+	}
+
+	for i, share := range manifestShares {
+		share.origin = fmt.Sprintf("manifest: share %d", i+1)
+		if manifest.Shares[i].Slug != "" {
+			share.origin = "manifest: " + share.Slug
+		}
+
+		if registry.lookupOrigin(share.origin) != nil {
+			continue
+		}
+
+		shareURLs, sharePaths, err := registerShare(registry, share, manifest.Shares[i].Paths, false, "", bases, errorChannel)
+		if err != nil {
+			closeListeners(listeners)
+
+			return err
+		}
+
+		urls = append(urls, shareURLs...)
+		paths = append(paths, sharePaths...)
+
+		created = append(created, createdShare{share, "manifest"})
+	}
+
+	if (len(urls) == 0 || len(paths) == 0) && Admin == "" && !Daemon && len(Watch) == 0 {
 		errorChannel <- Error{Message: ErrNoFile, Fatal: true}
 	}
 
 	for i := range urls {
-		fmt.Printf("%s | %s -> %s\n",
-			time.Now().Format(logDate),
-			urls[i],
-			paths[i])
+		logEvent(slog.LevelInfo, "share_registered", fmt.Sprintf("%s -> %s", urls[i], paths[i]),
+			"url", urls[i],
+			"path", paths[i])
 	}
 
+	var burning, watching []string
+
+	if Burn {
+		watching = absolutePaths(Watch)
+	}
+
+	for _, c := range created {
+		if c.share.Burn {
+			burning = append(burning, burnPaths(c.share)...)
+		}
+	}
+
+	// Nothing is announced until burning has been confirmed, so that no
+	// links are sent for shares which are then withdrawn
+	err = confirmBurn(burning, watching)
+	if err != nil {
+		for _, c := range created {
+			registry.remove(c.share)
+		}
+
+		closeListeners(listeners)
+
+		return err
+	}
+
+	for _, c := range created {
+		limits.emit(shareEvent("share_created", c.source, c.share, bases))
+
+		limits.mailer.queueShare(shareStatus(c.share, bases, time.Now()))
+	}
+
+	for _, dir := range Watch {
+		watcher, err := watchDirectory(dir, registry, limits, bases, errorChannel)
+		if err != nil {
+			closeListeners(listeners)
+
+			return fmt.Errorf("watching %s: %w", dir, err)
+		}
+		defer watcher.Close()
+
+		logEvent(slog.LevelInfo, "watching", fmt.Sprintf("Watching %s for new files", watcher.dir),
+			"path", watcher.dir)
+	}
+
+	limits.metrics.deadline = deadline
+
 	if Timeout != 0 {
-		time.AfterFunc(Timeout, func() {
-			err := srv.Shutdown(context.Background())
+		time.AfterFunc(time.Until(deadline), func() {
+			shutdown(fmt.Sprintf("Timeout of %s reached", Timeout))
+		})
+	}
 
-			errorChannel <- Error{Message: err}
+	if Idle != 0 {
+		limits.idle = newIdleTimer(Idle, func() {
+			shutdown(fmt.Sprintf("No downloads started in %s", Idle))
 		})
+	}
 
-		if TimeoutInterval > 0 {
-			fmt.Printf("%s | Shutdown in %s\n", time.Now().Format(logDate), Timeout)
+	if _, next := registry.nextExpiry(); TimeoutInterval > 0 && (Timeout != 0 || Idle != 0 || !next.IsZero()) {
+		printCountdown(deadline, registry, limits)
 
-			ticker := time.NewTicker(TimeoutInterval)
+		ticker := time.NewTicker(TimeoutInterval)
 
-			go func() {
-				for range ticker.C {
-					remains := Timeout - time.Since(startTime).Round(time.Second)
+		go func() {
+			for range ticker.C {
+				printCountdown(deadline, registry, limits)
+			}
+		}()
+	}
 
-					if remains > 0 {
-						fmt.Printf("%s | Shutdown in %s\n", time.Now().Format(logDate), Timeout-time.Since(startTime).Round(time.Second))
-					}
-				}
-			}()
-		}
+	serveErrors := make(chan error, len(listeners))
+
+	for _, admin := range adminServers {
+		logEvent(slog.LevelInfo, "listening", fmt.Sprintf("%s listening on %s", admin.name, admin.listener),
+			"listener", admin.listener.String(),
+			"admin", true)
+
+		go func() {
+			err := admin.serve()
+			if !errors.Is(err, http.ErrServerClosed) {
+				errorChannel <- Error{Message: err, Fatal: true}
+			}
+		}()
 	}
 
-	var err error
+	for _, l := range listeners {
+		logEvent(slog.LevelInfo, "listening", fmt.Sprintf("Listening on %s", l),
+			"listener", l.String(),
+			"admin", false)
 
-	if TLSKey != "" && TLSCert != "" {
-		fmt.Printf("%s | Listening on https://%s/\n",
-			time.Now().Format(logDate),
-			srv.Addr)
+		go func() {
+			if l.TLS {
+				serveErrors <- srv.ServeTLS(l, TLSCert, TLSKey)
+			} else {
+				serveErrors <- srv.Serve(l)
+			}
+		}()
+	}
 
-		err = srv.ListenAndServeTLS(TLSCert, TLSKey)
-	} else {
-		fmt.Printf("%s | Listening on http://%s/\n",
-			time.Now().Format(logDate),
-			srv.Addr)
+	for range listeners {
+		err := <-serveErrors
+		if !errors.Is(err, http.ErrServerClosed) {
+			srv.Close()
 
-		err = srv.ListenAndServe()
+			return err
+		}
 	}
 
-	fmt.Printf("%s | Shutting down...\n", time.Now().Format(logDate))
+	<-drained
 
-	if !errors.Is(err, http.ErrServerClosed) {
-		return err
+	for _, admin := range adminServers {
+		admin.Close()
 	}
 
+	limits.hooks.Wait()
+
+	limits.mailer.Wait()
+
+	errorChannel <- Error{}
+
+	logEvent(slog.LevelInfo, "shutdown", "Shutting down...")
+
+	limits.emit(&Event{Type: "shutdown", Reason: shutdownReason})
+
 	return nil
 }
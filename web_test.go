@@ -0,0 +1,92 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setFlag sets a flag's variable for the rest of the test.
+func setFlag[T any](t *testing.T, p *T, value T) {
+	t.Helper()
+
+	previous := *p
+
+	*p = value
+
+	t.Cleanup(func() { *p = previous })
+}
+
+func newTestLimits() *Limits {
+	return &Limits{
+		metrics:   newCollector(),
+		throttle:  newLimiter(0, 0),
+		transfers: newTransfers(),
+	}
+}
+
+// addTestFile registers a share, if it is not already, and publishes a file
+// with the given content in it.
+func addTestFile(t *testing.T, registry *Registry, share *Share, name, content string) *File {
+	t.Helper()
+
+	if share.registry == nil {
+		err := registry.add(share)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file := &File{Name: name, Path: "/test" + name, content: []byte(content)}
+
+	err := registry.addFile(share, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+// serveTest sends a request to the share handler, failing the test on any
+// error it reports.
+func serveTest(t *testing.T, registry *Registry, limits *Limits, r *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	errorChannel := make(chan Error, 16)
+
+	w := httptest.NewRecorder()
+
+	shareHandler(registry, limits, errorChannel).ServeHTTP(w, r)
+
+	close(errorChannel)
+
+	for err := range errorChannel {
+		t.Errorf("unexpected error: %v", err.Message)
+	}
+
+	return w
+}
+
+// serveTestErrors sends a request to the share handler, returning any errors
+// it reports.
+func serveTestErrors(registry *Registry, limits *Limits, r *http.Request) (*httptest.ResponseRecorder, []error) {
+	errorChannel := make(chan Error, 16)
+
+	w := httptest.NewRecorder()
+
+	shareHandler(registry, limits, errorChannel).ServeHTTP(w, r)
+
+	close(errorChannel)
+
+	var errs []error
+
+	for err := range errorChannel {
+		errs = append(errs, err.Message)
+	}
+
+	return w, errs
+}
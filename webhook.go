@@ -0,0 +1,226 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// Number of times delivery of an event is attempted
+	webhookAttempts = 5
+
+	// Delay before the first retry, doubled for each one after it
+	webhookBackoff = time.Second
+
+	// Events waiting to be delivered beyond this are dropped
+	webhookQueueSize = 1024
+
+	// How long retries may continue once shutting down
+	webhookShutdownTimeout = 10 * time.Second
+)
+
+var (
+	ErrInvalidWebhook = errors.New("webhook must be an http or https URL")
+)
+
+// Webhooks delivers events as signed JSON POST requests, each URL from its
+// own background goroutine so that a slow receiver neither delays serving
+// nor other receivers.
+type Webhooks struct {
+	secret  []byte
+	client  *http.Client
+	queues  map[string]chan *Event
+	backoff time.Duration
+	timeout time.Duration
+	stop    chan struct{}
+	stopped sync.WaitGroup
+	once    sync.Once
+
+	// Cancelled once shutdown gives up, interrupting both backoff and any
+	// request in flight
+	abort  context.Context
+	cancel context.CancelFunc
+}
+
+func isValidWebhooks(urls []string) bool {
+	for _, s := range urls {
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newWebhooks(urls []string, secret string) *Webhooks {
+	h := &Webhooks{
+		secret:  []byte(secret),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		queues:  make(map[string]chan *Event, len(urls)),
+		backoff: webhookBackoff,
+		timeout: webhookShutdownTimeout,
+		stop:    make(chan struct{}),
+	}
+
+	h.abort, h.cancel = context.WithCancel(context.Background())
+
+	for _, u := range urls {
+		queue := make(chan *Event, webhookQueueSize)
+
+		h.queues[u] = queue
+
+		h.stopped.Add(1)
+
+		go h.run(u, queue)
+	}
+
+	return h
+}
+
+// send queues an event for delivery to every URL, dropping it for any whose
+// queue is full rather than waiting.
+func (h *Webhooks) send(e *Event) {
+	if h == nil {
+		return
+	}
+
+	for u, queue := range h.queues {
+		select {
+		case queue <- e:
+		default:
+			logEvent(slog.LevelWarn, "webhook_dropped", fmt.Sprintf("Dropped %s event for webhook %s", e.Type, u),
+				"webhook", u,
+				"event_type", e.Type)
+		}
+	}
+}
+
+func (h *Webhooks) run(u string, queue chan *Event) {
+	defer h.stopped.Done()
+
+	for {
+		select {
+		case e := <-queue:
+			h.deliver(u, e)
+		case <-h.stop:
+			for {
+				select {
+				case e := <-queue:
+					h.deliver(u, e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver posts an event, retrying with exponential backoff until it is
+// accepted or the attempts run out.
+func (h *Webhooks) deliver(u string, e *Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	backoff := h.backoff
+
+	for attempt := 1; ; attempt++ {
+		err = h.post(u, e.Type, body)
+		if err == nil || attempt == webhookAttempts || !h.sleep(backoff) {
+			break
+		}
+
+		backoff *= 2
+	}
+
+	if err == nil {
+		return
+	}
+
+	logEvent(slog.LevelWarn, "webhook_failed", fmt.Sprintf("Failed to deliver %s event to webhook %s: %s", e.Type, u, err),
+		"webhook", u,
+		"event_type", e.Type,
+		"error", err.Error())
+}
+
+// sleep waits for d, returning false early if shutdown has given up on
+// retries.
+func (h *Webhooks) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-h.abort.Done():
+		return false
+	}
+}
+
+func (h *Webhooks) post(u, event string, body []byte) error {
+	req, err := http.NewRequestWithContext(h.abort, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "send/"+ReleaseVersion)
+	req.Header.Set("X-Send-Event", event)
+
+	if len(h.secret) > 0 {
+		mac := hmac.New(sha256.New, h.secret)
+
+		mac.Write(body)
+
+		req.Header.Set("X-Send-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Shutdown delivers any events still queued and stops the delivery
+// goroutines, abandoning retries after webhookShutdownTimeout.
+func (h *Webhooks) Shutdown() {
+	if h == nil {
+		return
+	}
+
+	h.once.Do(func() {
+		close(h.stop)
+
+		timer := time.AfterFunc(h.timeout, h.cancel)
+		defer timer.Stop()
+
+		h.stopped.Wait()
+
+		h.cancel()
+	})
+}
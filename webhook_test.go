@@ -0,0 +1,149 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	type request struct {
+		body      []byte
+		event     string
+		signature string
+	}
+
+	received := make(chan request, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		received <- request{body, r.Header.Get("X-Send-Event"), r.Header.Get("X-Send-Signature")}
+	}))
+	defer server.Close()
+
+	hooks := newWebhooks([]string{server.URL}, "secret")
+
+	hooks.send(&Event{Type: "download_completed", Slug: "/abc"})
+
+	hooks.Shutdown()
+
+	got := <-received
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+
+	mac.Write(got.body)
+
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got.signature != want {
+		t.Errorf("X-Send-Signature = %q, want %q", got.signature, want)
+	}
+
+	if got.event != "download_completed" {
+		t.Errorf("X-Send-Event = %q, want download_completed", got.event)
+	}
+
+	var event Event
+
+	err := json.Unmarshal(got.body, &event)
+	if err != nil || event.Slug != "/abc" {
+		t.Errorf("body = %s, want the event as JSON", got.body)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	hooks := newWebhooks([]string{server.URL}, "")
+
+	hooks.backoff = time.Millisecond
+
+	hooks.send(&Event{Type: "share_created"})
+
+	hooks.Shutdown()
+
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("attempts = %d, want delivery to stop once accepted on the third", got)
+	}
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hooks := newWebhooks([]string{server.URL}, "")
+
+	hooks.backoff = time.Millisecond
+
+	hooks.send(&Event{Type: "share_created"})
+
+	hooks.Shutdown()
+
+	if got := attempts.Load(); got != webhookAttempts {
+		t.Errorf("attempts = %d, want %d", got, webhookAttempts)
+	}
+}
+
+func TestWebhookShutdownAbandons(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"backoff", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}},
+		{"request", func(w http.ResponseWriter, r *http.Request) {
+			// The server only notices the client going away once the body
+			// has been read
+			io.Copy(io.Discard, r.Body)
+
+			<-r.Context().Done()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			hooks := newWebhooks([]string{server.URL}, "")
+
+			hooks.backoff = time.Hour
+			hooks.timeout = 50 * time.Millisecond
+
+			hooks.send(&Event{Type: "share_created"})
+
+			started := time.Now()
+
+			hooks.Shutdown()
+
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Errorf("Shutdown took %s, want it to give up after %s", elapsed, hooks.timeout)
+			}
+		})
+	}
+}